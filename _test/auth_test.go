@@ -0,0 +1,66 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	auth := etcd.NewAuth(client)
+	ctx := context.Background()
+
+	enabled, err := auth.AuthStatus(ctx)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	assert.NoError(t, auth.RoleAdd(ctx, "test-reader"))
+	assert.NoError(t, auth.RoleGrantPermission(ctx, "test-reader", etcd.Permission{Key: "/app/", IsPrefix: true, Type: "read"}))
+	role, err := auth.RoleGet(ctx, "test-reader")
+	assert.NoError(t, err)
+	assert.Equal(t, []etcd.Permission{{Key: "/app/", IsPrefix: true, Type: etcd.PermRead}}, role.Permissions)
+	assert.Error(t, auth.RoleGrantPermission(ctx, "test-reader", etcd.Permission{Key: "/app/", Type: "exec"}))
+
+	assert.NoError(t, auth.UserAdd(ctx, "test-user", "123456"))
+	assert.NoError(t, auth.UserGrantRole(ctx, "test-user", "test-reader"))
+	user, err := auth.UserGet(ctx, "test-user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-reader"}, user.Roles)
+
+	// 声明式：只读改为读写，新增角色，撤销旧角色
+	policy := etcd.RBACPolicy{
+		Roles: []etcd.Role{
+			{Name: "test-reader", Permissions: []etcd.Permission{{Key: "/app/", IsPrefix: true, Type: etcd.PermReadWrite}}},
+			{Name: "test-writer", Permissions: []etcd.Permission{{Key: "/app/a", RangeEnd: "/app/z", Type: etcd.PermWrite}}},
+		},
+		Users:  []etcd.UserPolicy{{Name: "test-user", Roles: []string{"test-writer"}}},
+		DryRun: true,
+	}
+	changes, err := auth.ApplyPolicy(ctx, policy)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 5)
+	role, _ = auth.RoleGet(ctx, "test-reader")
+	assert.Equal(t, etcd.PermRead, role.Permissions[0].Type)
+
+	policy.DryRun = false
+	changes, err = auth.ApplyPolicy(ctx, policy)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 5)
+	role, _ = auth.RoleGet(ctx, "test-reader")
+	assert.Equal(t, etcd.PermReadWrite, role.Permissions[0].Type)
+	user, _ = auth.UserGet(ctx, "test-user")
+	assert.Equal(t, []string{"test-writer"}, user.Roles)
+
+	// 已一致时没有变更
+	changes, err = auth.ApplyPolicy(ctx, policy)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	assert.NoError(t, auth.UserDelete(ctx, "test-user"))
+	assert.NoError(t, auth.RoleDelete(ctx, "test-reader"))
+	assert.NoError(t, auth.RoleDelete(ctx, "test-writer"))
+}
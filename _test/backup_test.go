@@ -0,0 +1,52 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	dir := t.TempDir()
+
+	header, _ := client.Put("/backup/a1", "1")
+	var progress int64
+	info, err := etcd.Backup(context.Background(), client, filepath.Join(dir, "snapshot.db"), func(written int64) { progress = written })
+	assert.NoError(t, err)
+	assert.Less(t, int64(0), info.Size)
+	assert.Equal(t, info.Size, progress)
+	assert.GreaterOrEqual(t, info.Revision, header.Revision)
+
+	// 校验文件
+	data, _ := os.ReadFile(info.Path)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), info.Sha256)
+	checksum, _ := os.ReadFile(info.Path + ".sha256")
+	assert.Equal(t, info.Sha256+"  snapshot.db\n", string(checksum))
+	_, err = os.Stat(info.Path + ".part")
+	assert.True(t, os.IsNotExist(err))
+
+	// 定时备份，只保留2份
+	scheduleDir := filepath.Join(dir, "schedule")
+	job, err := etcd.NewBackupSchedule(client, scheduleDir, time.Second, 2)
+	assert.NoError(t, err)
+	time.Sleep(3500 * time.Millisecond)
+	job.Close()
+	assert.NotNil(t, job.Last())
+	files, _ := filepath.Glob(filepath.Join(scheduleDir, "etcd-*.db"))
+	assert.Len(t, files, 2)
+
+	// 间隔不正确
+	_, err = etcd.NewBackupSchedule(client, scheduleDir, 0, 2)
+	assert.Error(t, err)
+	_, _ = client.Delete("/backup/a1")
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBarrier(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	barrier := etcd.NewBarrier(client, "/barrier/1")
+	assert.NoError(t, barrier.Hold())
+	assert.ErrorIs(t, etcd.NewBarrier(client, "/barrier/1").Hold(), etcd.ErrBarrierHeld)
+
+	var released int32
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		atomic.StoreInt32(&released, 1)
+		_ = barrier.Release()
+	}()
+	assert.NoError(t, etcd.NewBarrier(client, "/barrier/1").Wait(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&released))
+
+	// 未设置屏障时立即返回
+	assert.NoError(t, etcd.NewBarrier(client, "/barrier/1").Wait(context.Background()))
+}
+
+func TestDoubleBarrier(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	var entered int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			barrier := etcd.NewDoubleBarrier(client, "/doubleBarrier/1", 3)
+			atomic.AddInt32(&entered, 1)
+			assert.NoError(t, barrier.Enter(context.Background()))
+			// 所有客户端都进入后，才会继续执行
+			assert.Equal(t, int32(3), atomic.LoadInt32(&entered))
+			assert.NoError(t, barrier.Leave(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	results, _ := client.GetPrefixKey("/doubleBarrier/1")
+	assert.Len(t, results, 0)
+}
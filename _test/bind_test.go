@@ -0,0 +1,112 @@
+package test
+
+import (
+	"fmt"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bindDatabase struct {
+	Host string
+	Port int
+}
+
+type bindConfig struct {
+	Name     string
+	Enabled  bool
+	Database bindDatabase
+}
+
+func (receiver bindConfig) Validate() error {
+	if receiver.Name == "" {
+		return fmt.Errorf("Name不能为空")
+	}
+	return nil
+}
+
+func TestBindJson(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.PutJson("/bind/json", bindConfig{Name: "a", Database: bindDatabase{Host: "127.0.0.1", Port: 3306}})
+
+	binder, err := etcd.Bind[bindConfig](client, "/bind/json")
+	assert.NoError(t, err)
+	defer binder.Close()
+	assert.Equal(t, "a", binder.Get().Name)
+	assert.Equal(t, 3306, binder.Get().Database.Port)
+
+	// 通知在替换配置之后执行
+	var lock sync.Mutex
+	var oldName, newName string
+	binder.OnChange(func(old bindConfig, new bindConfig) {
+		lock.Lock()
+		defer lock.Unlock()
+		oldName, newName = old.Name, new.Name
+	})
+	changed := func(old, new string) func() bool {
+		return func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return oldName == old && newName == new
+		}
+	}
+
+	_, _ = client.PutJson("/bind/json", bindConfig{Name: "b"})
+	assert.Eventually(t, changed("a", "b"), time.Second, 10*time.Millisecond)
+	assert.Equal(t, "b", binder.Get().Name)
+
+	// 校验失败时，继续使用旧配置
+	_, _ = client.PutJson("/bind/json", bindConfig{Name: ""})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "b", binder.Get().Name)
+
+	// KEY被删除后，零值校验失败，继续使用旧配置
+	_, _ = client.Delete("/bind/json")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "b", binder.Get().Name)
+	assert.True(t, changed("a", "b")())
+}
+
+func TestBindDelete(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.PutJson("/bind/delete", bindDatabase{Host: "127.0.0.1", Port: 3306})
+
+	binder, err := etcd.Bind[bindDatabase](client, "/bind/delete")
+	assert.NoError(t, err)
+	defer binder.Close()
+	assert.Equal(t, 3306, binder.Get().Port)
+
+	// KEY被删除后，恢复为零值（没有实现IValidate）
+	header, _ := client.Delete("/bind/delete")
+	assert.Eventually(t, func() bool { return binder.Revision() == header.Revision }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, bindDatabase{}, binder.Get())
+}
+
+func TestBindTree(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.DeletePrefixKey("/bind/tree")
+	_, _ = client.Put("/bind/tree/Name", "a")
+	_, _ = client.Put("/bind/tree/Enabled", "true")
+	_, _ = client.Put("/bind/tree/Database/Host", "127.0.0.1")
+	_, _ = client.Put("/bind/tree/database/port", "3306")
+
+	binder, err := etcd.Bind[bindConfig](client, "/bind/tree")
+	assert.NoError(t, err)
+	defer binder.Close()
+	assert.Equal(t, "a", binder.Get().Name)
+	assert.True(t, binder.Get().Enabled)
+	assert.Equal(t, "127.0.0.1", binder.Get().Database.Host)
+	assert.Equal(t, 3306, binder.Get().Database.Port)
+
+	_, _ = client.Put("/bind/tree/database/port", "3307")
+	assert.Eventually(t, func() bool { return binder.Get().Database.Port == 3307 }, time.Second, 10*time.Millisecond)
+
+	_, err = etcd.Bind[bindConfig](client, "/bind/notExists")
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClientPool(t *testing.T) {
+	// 使用单独的名称注册，避免其它测试持有的引用影响引用数
+	assert.NoError(t, etcd.Register("pool", "Server=127.0.0.1:2379,DialTimeout=5000"))
+	defer etcd.Unregister("pool")
+
+	client1 := container.Resolve[etcd.IClient]("pool")
+	client2 := container.Resolve[etcd.IClient]("pool")
+
+	// 同一个配置共用一个连接
+	assert.Same(t, client1.Original(), client2.Original())
+
+	// Close只释放引用，不会关闭连接，之后的请求返回错误
+	client1.Close()
+	client1.Close()
+	_, err := client1.Put("/pool/a1", "1")
+	assert.Error(t, err)
+	_, err = client2.Put("/pool/a1", "1")
+	assert.NoError(t, err)
+
+	// 所有引用都释放后，连接仍然保留，再次取出时不需要重新连接
+	client2.Close()
+	client3 := container.Resolve[etcd.IClient]("pool")
+	assert.Same(t, client2.Original(), client3.Original())
+	result, err := client3.Get("/pool/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", result.Value)
+	client3.Close()
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/stretchr/testify/assert"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"testing"
+	"time"
+)
+
+func TestAutoCompaction(t *testing.T) {
+	// 压缩会影响其它测试，使用内存etcd
+	memory := etcd.NewMemoryEtcd()
+	defer memory.Close()
+	client, _ := memory.Client()
+	defer client.Close()
+
+	_, err := etcd.NewCompactor(client, etcd.CompactionConfig{Mode: "unknown", Retention: "1"})
+	assert.Error(t, err)
+	_, err = etcd.NewCompactor(client, etcd.CompactionConfig{Mode: etcd.CompactionPeriodic, Retention: "abc"})
+	assert.Error(t, err)
+	_, err = etcd.NewCompactor(client, etcd.CompactionConfig{Mode: etcd.CompactionRevision, Retention: "10", DefragWindow: "02:00"})
+	assert.Error(t, err)
+
+	_, _ = client.Put("/compactor/a1", "1")
+	header, err := client.Put("/compactor/a1", "2")
+	assert.NoError(t, err)
+
+	// 只有一个实例成为Leader，并立即压缩到保留1个版本
+	compactor1, err := etcd.NewCompactor(client, etcd.CompactionConfig{Mode: etcd.CompactionRevision, Retention: "1", ElectionKey: "/compactor/election"})
+	assert.NoError(t, err)
+	compactor2, err := etcd.NewCompactor(client, etcd.CompactionConfig{Mode: etcd.CompactionRevision, Retention: "1", ElectionKey: "/compactor/election"})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return compactor1.IsLeader() || compactor2.IsLeader() }, 5*time.Second, 100*time.Millisecond)
+	leader, follower := compactor1, compactor2
+	if compactor2.IsLeader() {
+		leader, follower = compactor2, compactor1
+	}
+	assert.Eventually(t, func() bool { return leader.LastRevision() >= header.Revision-1 }, 5*time.Second, 100*time.Millisecond)
+	assert.False(t, follower.IsLeader())
+	_, err = client.Original().Get(context.Background(), "/compactor/a1", etcdV3.WithRev(header.Revision-2))
+	assert.Error(t, err)
+
+	// Leader关闭后，由其它实例接管
+	leader.Close()
+	assert.Eventually(t, follower.IsLeader, 5*time.Second, 100*time.Millisecond)
+	follower.Close()
+}
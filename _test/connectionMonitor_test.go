@@ -0,0 +1,38 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionMonitor(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	_, err := client.Put("/monitor/a1", "1")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return etcd.Monitor(client).ConnectionState() == etcd.ConnectionStateReady
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// 首次检测到Leader时不会触发OnLeaderChange
+	var leaderChanges atomic.Int32
+	etcd.Monitor(client).OnLeaderChange(func(old uint64, new uint64) {
+		leaderChanges.Add(1)
+	})
+
+	// 首次检测完成后，能拿到当前节点、Leader
+	assert.Eventually(t, func() bool {
+		return etcd.Monitor(client).CurrentEndpoint() != "" && etcd.Monitor(client).Leader() > 0
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int32(0), leaderChanges.Load())
+
+	// 命名空间客户端共用同一个连接的监控
+	namespaceClient := client.WithNamespace("/monitor")
+	defer namespaceClient.Close()
+	assert.Equal(t, etcd.Monitor(client).CurrentEndpoint(), etcd.Monitor(namespaceClient).CurrentEndpoint())
+}
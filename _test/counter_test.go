@@ -0,0 +1,78 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestIncr(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.Delete("/counter/1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.Incr("/counter/1", 2)
+		}()
+	}
+	wg.Wait()
+
+	value, err := client.Decr("/counter/1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(19), value)
+
+	_, _ = client.Put("/counter/2", "a")
+	_, err = client.Incr("/counter/2", 1)
+	assert.Error(t, err)
+}
+
+func TestSequence(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.Delete("/sequence/1")
+
+	sequence1 := etcd.NewSequence(client, "/sequence/1", 10)
+	sequence2 := etcd.NewSequence(client, "/sequence/1", 10)
+
+	id, _ := sequence1.Next()
+	assert.Equal(t, int64(1), id)
+	id, _ = sequence2.Next()
+	assert.Equal(t, int64(11), id)
+	id, _ = sequence1.Next()
+	assert.Equal(t, int64(2), id)
+
+	ids := make(map[int64]struct{})
+	for i := 0; i < 30; i++ {
+		id, _ = sequence1.Next()
+		ids[id] = struct{}{}
+	}
+	assert.Len(t, ids, 30)
+}
+
+func TestWorkerId(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	workerId1, err := etcd.NewWorkerId(client, "/workerId/1", 2)
+	assert.NoError(t, err)
+	workerId2, _ := etcd.NewWorkerId(client, "/workerId/1", 2)
+	assert.NotEqual(t, workerId1.Id(), workerId2.Id())
+
+	// 编号已用完
+	_, err = etcd.NewWorkerId(client, "/workerId/1", 2)
+	assert.Error(t, err)
+
+	// 释放后可以重新分配
+	_ = workerId1.Close()
+	workerId3, err := etcd.NewWorkerId(client, "/workerId/1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, workerId1.Id(), workerId3.Id())
+	_ = workerId2.Close()
+	_ = workerId3.Close()
+}
@@ -0,0 +1,35 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDiagnose(t *testing.T) {
+	// 每个节点单独诊断
+	report, err := etcd.Diagnose("Server=127.0.0.1:2379|127.0.0.1:2380,DialTimeout=1000", time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, report.Endpoints, 2)
+	assert.True(t, report.Endpoints[0].Reachable)
+	assert.NotEmpty(t, report.Endpoints[0].Version)
+	assert.Less(t, uint64(0), report.Endpoints[0].Leader)
+	assert.False(t, report.Endpoints[1].Reachable)
+	assert.Equal(t, 1, report.Reachable())
+	assert.False(t, report.Healthy())
+	// 不可达的节点不影响其它请求的超时
+	assert.Equal(t, "", report.AuthError)
+
+	_, err = etcd.Diagnose("DialTimeout=1000", time.Second)
+	assert.Error(t, err)
+
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	report, err = etcd.DiagnoseClient(context.Background(), client)
+	assert.NoError(t, err)
+	assert.True(t, report.Healthy())
+	assert.Equal(t, "", report.AuthError)
+}
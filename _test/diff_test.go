@@ -0,0 +1,70 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestDiffSync(t *testing.T) {
+	src := container.Resolve[etcd.IClient]("default")
+	dst := container.Resolve[etcd.IClient]("cache")
+	defer src.Close()
+	defer dst.Close()
+
+	_, _ = src.Put("/diff/src/a1", "1")
+	_, _ = src.Put("/diff/src/a2", "2")
+	_, _ = dst.Put("/diff/dst/a2", "0")
+	_, _ = dst.Put("/diff/dst/a3", "3")
+
+	result, err := etcd.Diff(src, "/diff/src/", dst, "/diff/dst/")
+	assert.NoError(t, err)
+	assert.Equal(t, "a1", result.Added[0].Key)
+	assert.Equal(t, "a2", result.Changed[0].Key)
+	assert.Equal(t, "a3", result.Removed[0].Key)
+	assert.Equal(t, "+ a1\n~ a2\n- a3", result.String())
+
+	// 目标客户端开启了本地缓存
+	kv, _ := dst.Get("/diff/dst/a2")
+	assert.Equal(t, "0", kv.Value)
+
+	// 默认保留目标多出来的KEY
+	result, err = etcd.Sync(src, "/diff/src/", dst, "/diff/dst/", etcd.SyncOptions{BatchSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "a2"}, result.Applied)
+	kv, _ = dst.Get("/diff/dst/a2")
+	assert.Equal(t, "2", kv.Value)
+	result, _ = etcd.Diff(src, "/diff/src/", dst, "/diff/dst/")
+	assert.Len(t, result.Removed, 1)
+	assert.Empty(t, result.Added)
+	assert.Empty(t, result.Changed)
+
+	_, err = etcd.Sync(src, "/diff/src/", dst, "/diff/dst/", etcd.SyncOptions{Delete: true})
+	assert.NoError(t, err)
+	result, _ = etcd.Diff(src, "/diff/src/", dst, "/diff/dst/")
+	assert.True(t, result.IsEmpty())
+
+	_, _ = src.DeletePrefixKey("/diff/")
+}
+
+func TestSyncBatchSize(t *testing.T) {
+	src := container.Resolve[etcd.IClient]("default")
+	dst := container.Resolve[etcd.IClient]("cache")
+	defer src.Close()
+	defer dst.Close()
+
+	for i := 0; i < 200; i++ {
+		_, _ = src.Put("/syncBatch/src/"+strconv.Itoa(i), "1")
+	}
+
+	// 超过etcd单个事务的操作数量限制（128）时，按最大128分批写入
+	result, err := etcd.Sync(src, "/syncBatch/src/", dst, "/syncBatch/dst/", etcd.SyncOptions{BatchSize: 500})
+	assert.NoError(t, err)
+	assert.Len(t, result.Applied, 200)
+	result, _ = etcd.Diff(src, "/syncBatch/src/", dst, "/syncBatch/dst/")
+	assert.True(t, result.IsEmpty())
+
+	_, _ = src.DeletePrefixKey("/syncBatch/")
+}
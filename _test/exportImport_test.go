@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	ctx := context.Background()
+
+	_, _ = client.Put("/export/src/a1", "1")
+	_, _ = client.Put("/export/src/db/port", "3306")
+	_, _ = client.Put("/export/src/bin", string([]byte{0xff, 0xfe}))
+	leaseId, _ := client.LeaseGrant(60)
+	_, _ = client.PutLease("/export/src/lease", "2", leaseId)
+
+	for _, format := range []etcd.ExportFormat{etcd.FormatJson, etcd.FormatYaml, etcd.FormatNdjson} {
+		_, _ = client.DeletePrefixKey("/export/dst/")
+
+		var buffer bytes.Buffer
+		count, err := etcd.Export(ctx, client, "/export/src/", format, &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+
+		// 导入到另一个前缀
+		result, err := etcd.Import(ctx, client, "/export/dst/", format, bytes.NewReader(buffer.Bytes()), etcd.ImportOptions{BatchSize: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.Created)
+
+		kvs, _ := client.GetPrefixKey("/export/dst/")
+		assert.Len(t, kvs, 4)
+		assert.Equal(t, "3306", kvs["/export/dst/db/port"].Value)
+		assert.Equal(t, string([]byte{0xff, 0xfe}), kvs["/export/dst/bin"].Value)
+		assert.NotEqual(t, int64(0), kvs["/export/dst/lease"].Lease)
+
+		// 冲突处理
+		_, _ = client.Put("/export/dst/a1", "changed")
+		result, err = etcd.Import(ctx, client, "/export/dst/", format, bytes.NewReader(buffer.Bytes()), etcd.ImportOptions{Conflict: etcd.ConflictSkip})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.Skipped)
+		result, err = etcd.Import(ctx, client, "/export/dst/", format, bytes.NewReader(buffer.Bytes()), etcd.ImportOptions{Conflict: etcd.ConflictFail})
+		assert.Error(t, err)
+		result, err = etcd.Import(ctx, client, "/export/dst/", format, bytes.NewReader(buffer.Bytes()), etcd.ImportOptions{DryRun: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.Updated)
+		kv, _ := client.Get("/export/dst/a1")
+		assert.Equal(t, "changed", kv.Value)
+	}
+
+	_, err := etcd.Import(ctx, client, "/export/dst/", etcd.FormatNdjson, strings.NewReader("{bad"), etcd.ImportOptions{})
+	assert.Error(t, err)
+
+	// 超过etcd每个事务的操作数限制时，按最大128个KEY分批
+	var lines strings.Builder
+	for i := 0; i < 200; i++ {
+		lines.WriteString(fmt.Sprintf("{\"key\":\"batch/%d\",\"value\":\"%d\"}\n", i, i))
+	}
+	result, err := etcd.Import(ctx, client, "/export/dst/", etcd.FormatNdjson, strings.NewReader(lines.String()), etcd.ImportOptions{BatchSize: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, 200, result.Created)
+
+	_, _ = client.DeletePrefixKey("/export/")
+	_, _ = client.LeaseRevoke(leaseId)
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("failover")
+	defer client.Close()
+
+	var failover atomic.Value
+	etcd.OnFailover(client, func(from string, to string) {
+		failover.Store(from + "->" + to)
+	})
+
+	// 切换前注册的Watch，切换后在备用集群上重新注册
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var watchValue atomic.Value
+	var watchCount atomic.Int32
+	startAt := time.Now()
+	client.Watch(ctx, "/failover/a1", func(event etcd.WatchEvent) {
+		watchValue.Store(event.Kv.Value)
+		watchCount.Add(1)
+	})
+	// 主集群不可用时，注册Watch不会阻塞
+	assert.Less(t, time.Since(startAt), time.Second)
+
+	assert.Equal(t, etcd.ClusterPrimary, etcd.ActiveCluster(client))
+	// 主集群连续2次健康检查失败后切换到备用集群
+	assert.Eventually(t, func() bool {
+		return etcd.ActiveCluster(client) == etcd.ClusterStandby
+	}, 30*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "primary->standby", failover.Load())
+
+	_, err := client.Put("/failover/a1", "1")
+	assert.NoError(t, err)
+	result, err := client.Get("/failover/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Eventually(t, func() bool {
+		return watchValue.Load() == "1"
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// 通过备用集群直接写入，同样可以收到
+	standbyClient := container.Resolve[etcd.IClient]("default")
+	defer standbyClient.Close()
+	_, _ = standbyClient.Put("/failover/a1", "2")
+	assert.Eventually(t, func() bool {
+		return watchValue.Load() == "2"
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int32(2), watchCount.Load())
+
+	// 命名空间客户端共用切换状态
+	assert.Equal(t, etcd.ClusterStandby, etcd.ActiveCluster(client.WithNamespace("/failover")))
+
+	// 未配置备用集群的客户端
+	defaultClient := container.Resolve[etcd.IClient]("default")
+	defer defaultClient.Close()
+	assert.Equal(t, etcd.ClusterPrimary, etcd.ActiveCluster(defaultClient))
+}
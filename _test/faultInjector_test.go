@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+func TestFaultInjector(t *testing.T) {
+	faults := etcd.NewFaultInjector()
+	client := faults.Wrap(container.Resolve[etcd.IClient]("default"))
+	defer client.Close()
+	_, _ = client.DeletePrefixKey("/fault/")
+
+	// 按KEY注入错误
+	ruleId := faults.Add(etcd.FaultRule{Kind: etcd.FaultError, KeyPattern: "/fault/err/*", Err: rpctypes.ErrNoLeader})
+	_, err := client.Put("/fault/err/a1", "1")
+	assert.ErrorIs(t, err, rpctypes.ErrNoLeader)
+	_, err = client.Put("/fault/a1", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), faults.Injected(etcd.FaultError))
+
+	// 关闭后不再注入
+	faults.Disable()
+	_, err = client.Put("/fault/err/a1", "1")
+	assert.NoError(t, err)
+	faults.Enable()
+	faults.Remove(ruleId)
+	_, err = client.Put("/fault/err/a1", "1")
+	assert.NoError(t, err)
+
+	// 延迟
+	faults.Add(etcd.FaultRule{Kind: etcd.FaultLatency, KeyPattern: "/fault/slow", Delay: 200 * time.Millisecond})
+	startAt := time.Now()
+	_, _ = client.Get("/fault/slow")
+	assert.GreaterOrEqual(t, time.Since(startAt), 200*time.Millisecond)
+
+	// 概率
+	faults.Clear()
+	faults.Seed(1)
+	faults.Add(etcd.FaultRule{Kind: etcd.FaultError, KeyPattern: "/fault/random", Probability: 0.5})
+	var failed int
+	for i := 0; i < 200; i++ {
+		if _, err = client.Get("/fault/random"); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 100, failed, 30)
+	faults.Clear()
+
+	// 丢弃Watch事件、关闭Watch
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events int32
+	client.WatchPrefixKey(ctx, "/fault/watch/", func(event etcd.WatchEvent) {
+		atomic.AddInt32(&events, 1)
+	})
+	time.Sleep(100 * time.Millisecond)
+	faults.Add(etcd.FaultRule{Kind: etcd.FaultDropWatchEvent, KeyPattern: "/fault/watch/drop"})
+	faults.Add(etcd.FaultRule{Kind: etcd.FaultCloseWatch, KeyPattern: "/fault/watch/close"})
+	_, _ = client.Put("/fault/watch/a1", "1")
+	_, _ = client.Put("/fault/watch/drop", "1")
+	_, _ = client.Put("/fault/watch/a2", "1")
+	_, _ = client.Put("/fault/watch/close", "1")
+	_, _ = client.Put("/fault/watch/a3", "1")
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&events))
+	faults.Clear()
+
+	// 租约提前到期
+	faults.Add(etcd.FaultRule{Kind: etcd.FaultExpireLease, KeyPattern: "/fault/lease", Delay: 100 * time.Millisecond})
+	_, _ = client.Put("/fault/lease", "1")
+	leaseId, err := client.LeaseGrant(60, "/fault/lease")
+	assert.NoError(t, err)
+	assert.True(t, client.Exists("/fault/lease"))
+	assert.Eventually(t, func() bool { return !client.Exists("/fault/lease") }, 3*time.Second, 20*time.Millisecond)
+	info, _ := client.LeaseInfo(leaseId)
+	assert.Equal(t, int64(-1), info.TTL)
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGetCache(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("cache")
+	defer client.Close()
+	other := container.Resolve[etcd.IClient]("default")
+	defer other.Close()
+
+	// 连接在多个测试之间共用，统计按差值比较
+	before := etcd.GetCacheStats(client)
+	_, _ = client.Put("/cache/a", "1")
+	result, _ := client.Get("/cache/a")
+	assert.Equal(t, "1", result.Value)
+	result, _ = client.Get("/cache/a")
+	assert.Equal(t, "1", result.Value)
+
+	stats := etcd.GetCacheStats(client)
+	assert.Equal(t, int64(1), stats.Hits-before.Hits)
+	assert.Equal(t, int64(1), stats.Misses-before.Misses)
+
+	// 其它客户端修改后，通过Watch失效
+	_, _ = other.Put("/cache/a", "2")
+	time.Sleep(100 * time.Millisecond)
+	result, _ = client.Get("/cache/a")
+	assert.Equal(t, "2", result.Value)
+
+	// 计数器修改后立即失效
+	_, _ = client.Put("/cache/a", "1")
+	_, _ = client.Get("/cache/a")
+	_, _ = client.Incr("/cache/a", 2)
+	result, _ = client.Get("/cache/a")
+	assert.Equal(t, "3", result.Value)
+	_, _ = client.Decr("/cache/a", 1)
+	result, _ = client.Get("/cache/a")
+	assert.Equal(t, "2", result.Value)
+
+	// 超出容量，淘汰最久未使用的
+	_, _ = client.Get("/cache/b")
+	_, _ = client.Get("/cache/c")
+	_, _ = client.Get("/cache/d")
+	stats = etcd.GetCacheStats(client)
+	assert.Equal(t, 2, stats.Size)
+	assert.Greater(t, stats.Evictions, before.Evictions)
+}
@@ -9,5 +9,8 @@ import (
 func init() {
 	// 设置配置默认值，模拟配置文件
 	configure.SetDefault("Etcd.default", "Server=127.0.0.1:2379|127.0.0.1:2379,DialTimeout=5000")
+	configure.SetDefault("Etcd.cache", "Server=127.0.0.1:2379,DialTimeout=5000,CacheSize=2")
+	// 主集群不可用，用于测试切换到备用集群
+	configure.SetDefault("Etcd.failover", "Server=127.0.0.1:23790,StandbyServer=127.0.0.1:2379,DialTimeout=5000,FailoverInterval=200,FailoverThreshold=2")
 	fs.Initialize[etcd.Module]("test etcd")
 }
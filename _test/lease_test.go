@@ -12,6 +12,7 @@ import (
 
 func TestLease(t *testing.T) {
 	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
 
 	_, _ = client.PutJson("/test/lease3", []int{3})
 
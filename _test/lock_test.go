@@ -11,6 +11,7 @@ import (
 
 func TestLock(t *testing.T) {
 	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
 
 	result := 0
 	unLock1, _ := client.Lock("/lock/1", 1)
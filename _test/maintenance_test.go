@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	maintenance := etcd.NewMaintenance(client)
+	ctx := context.Background()
+
+	members, err := maintenance.MemberList(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, members)
+
+	status, err := maintenance.EndpointStatus(ctx, "127.0.0.1:2379")
+	assert.NoError(t, err)
+	assert.True(t, status.Reachable)
+	assert.Equal(t, members[0].ID, status.MemberId)
+
+	alarms, err := maintenance.AlarmList(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, alarms)
+	assert.Error(t, maintenance.AlarmDisarm(ctx, etcd.Alarm{Type: "UNKNOWN"}))
+
+	// 压缩到当前版本后，两次计算的哈希一致
+	header, err := client.Put("/maintenance/a1", "1")
+	assert.NoError(t, err)
+	assert.NoError(t, maintenance.Compact(ctx, header.Revision, true))
+	hash1, err := maintenance.HashKV(ctx, "127.0.0.1:2379", header.Revision)
+	assert.NoError(t, err)
+	hash2, err := maintenance.HashKV(ctx, "127.0.0.1:2379", header.Revision)
+	assert.NoError(t, err)
+	assert.Equal(t, hash1.Hash, hash2.Hash)
+
+	assert.NoError(t, maintenance.Defragment(ctx, "127.0.0.1:2379"))
+	_, _ = client.Delete("/maintenance/a1")
+}
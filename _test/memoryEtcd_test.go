@@ -0,0 +1,137 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryEtcd(t *testing.T) {
+	memory := etcd.RegisterMemory("memory")
+	defer memory.Close()
+
+	client := container.Resolve[etcd.IClient]("memory")
+	defer client.Close()
+
+	// Revision、Version
+	header, err := client.Put("/memory/a1", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), header.Revision)
+	_, _ = client.Put("/memory/a1", "2")
+	result, err := client.Get("/memory/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result.Value)
+	assert.Equal(t, int64(2), result.Version)
+	assert.Equal(t, int64(2), result.CreateRevision)
+	assert.Equal(t, int64(3), result.ModRevision)
+
+	// 前缀监听
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events int32
+	client.WatchPrefixKey(ctx, "/memory/", func(event etcd.WatchEvent) {
+		atomic.AddInt32(&events, 1)
+	})
+	time.Sleep(100 * time.Millisecond)
+	_, _ = client.Put("/memory/a2", "1")
+	_, _ = client.Delete("/memory/a2")
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&events) == 2 }, 3*time.Second, 10*time.Millisecond)
+
+	// 租约在模拟的时钟到期后删除
+	leaseId, err := client.LeaseGrant(10, "/memory/a1")
+	assert.NoError(t, err)
+	memory.Advance(9 * time.Second)
+	assert.True(t, client.Exists("/memory/a1"))
+	info, _ := client.LeaseInfo(leaseId)
+	assert.Equal(t, int64(1), info.TTL)
+	memory.Advance(time.Second)
+	assert.False(t, client.Exists("/memory/a1"))
+
+	// 事务
+	rsp, err := client.Original().Txn(context.Background()).
+		If(etcdV3.Compare(etcdV3.CreateRevision("/memory/a3"), "=", 0)).
+		Then(etcdV3.OpPut("/memory/a3", "1")).
+		Commit()
+	assert.NoError(t, err)
+	assert.True(t, rsp.Succeeded)
+	count, err := client.Incr("/memory/a3", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// 锁
+	unLock, err := client.Lock("/memory/lock", 5)
+	assert.NoError(t, err)
+	unLock()
+
+	// 压缩后不能读取历史版本
+	revision := memory.Revision()
+	err = etcd.NewMaintenance(client).Compact(context.Background(), revision, false)
+	assert.NoError(t, err)
+	_, err = client.Original().Get(context.Background(), "/memory/a3", etcdV3.WithRev(revision-1))
+	assert.Error(t, err)
+
+	// 同一个注册共用一个连接
+	client2 := container.Resolve[etcd.IClient]("memory")
+	defer client2.Close()
+	assert.Same(t, client.Original(), client2.Original())
+	result, _ = client2.Get("/memory/a3")
+	assert.Equal(t, "3", result.Value)
+}
+
+func TestMemoryEtcdWatch(t *testing.T) {
+	memory := etcd.NewMemoryEtcd()
+	defer memory.Close()
+	client, _ := memory.Client()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	next := func(watch etcdV3.WatchChan) etcdV3.WatchResponse {
+		select {
+		case rsp := <-watch:
+			return rsp
+		case <-time.After(3 * time.Second):
+			t.Fatal("没有收到Watch的响应")
+			return etcdV3.WatchResponse{}
+		}
+	}
+
+	// 开始的Revision大于当前Revision时，之前的变化不发送
+	watch := client.Original().Watch(ctx, "/memory/", etcdV3.WithPrefix(), etcdV3.WithRev(memory.Revision()+2))
+	_, _ = client.Put("/memory/a1", "1")
+	_, _ = client.Put("/memory/a1", "2")
+	rsp := next(watch)
+	assert.Len(t, rsp.Events, 1)
+	assert.Equal(t, "2", string(rsp.Events[0].Kv.Value))
+
+	// 一个间隔内没有变化时，发送进度通知
+	progress := client.Original().Watch(ctx, "/memory/", etcdV3.WithPrefix(), etcdV3.WithProgressNotify())
+	time.Sleep(100 * time.Millisecond)
+	memory.Advance(10 * time.Minute)
+	rsp = next(progress)
+	assert.True(t, rsp.IsProgressNotify())
+	assert.Equal(t, memory.Revision(), rsp.Header.Revision)
+
+	// 间隔内有变化时，不发送进度通知
+	_, _ = client.Put("/memory/a2", "1")
+	rsp = next(progress)
+	assert.Len(t, rsp.Events, 1)
+	memory.Advance(10 * time.Minute)
+	_, _ = client.Put("/memory/a2", "2")
+	rsp = next(progress)
+	assert.Len(t, rsp.Events, 1)
+
+	// 压缩后，可以从压缩时的Revision开始Watch，之前的Revision返回已压缩
+	revision := memory.Revision()
+	_ = etcd.NewMaintenance(client).Compact(context.Background(), revision, false)
+	rsp = next(client.Original().Watch(ctx, "/memory/", etcdV3.WithPrefix(), etcdV3.WithRev(revision)))
+	assert.Len(t, rsp.Events, 1)
+	assert.Equal(t, revision, rsp.Events[0].Kv.ModRevision)
+	rsp = next(client.Original().Watch(ctx, "/memory/", etcdV3.WithPrefix(), etcdV3.WithRev(revision-1)))
+	assert.Equal(t, revision, rsp.CompactRevision)
+}
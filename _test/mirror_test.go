@@ -0,0 +1,36 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.DeletePrefixKey("/mirror/")
+	_, _ = client.Put("/mirror/a", "1")
+
+	mirror := etcd.NewMirror(client, "/mirror/")
+	defer mirror.Close()
+	assert.NoError(t, mirror.WaitReady(context.Background()))
+	assert.True(t, mirror.IsReady())
+
+	kv, exists := mirror.Get("/mirror/a")
+	assert.True(t, exists)
+	assert.Equal(t, "1", kv.Value)
+
+	putRsp, _ := client.Put("/mirror/b", "2")
+	_, _ = client.Delete("/mirror/a")
+	time.Sleep(100 * time.Millisecond)
+
+	_, exists = mirror.Get("/mirror/a")
+	assert.False(t, exists)
+	assert.Equal(t, 1, mirror.Len())
+	assert.Equal(t, "2", mirror.List()["/mirror/b"].Value)
+	assert.Less(t, putRsp.Revision, mirror.Revision())
+}
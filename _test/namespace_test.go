@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	teamA := client.WithNamespace("/teamA")
+	defer teamA.Close()
+	assert.Equal(t, "/teamA", teamA.Namespace())
+
+	var watchResult sync.Map
+	teamA.WatchPrefixKey(context.TODO(), "/ns/", func(event etcd.WatchEvent) {
+		watchResult.Store(event.Kv.Key, event.Kv.Value)
+	})
+
+	_, _ = teamA.Put("/ns/a1", "1")
+	result, _ := client.Get("/teamA/ns/a1")
+	assert.Equal(t, "1", result.Value)
+
+	result, _ = teamA.Get("/ns/a1")
+	assert.Equal(t, "/ns/a1", result.Key)
+	assert.Equal(t, "1", result.Value)
+	assert.False(t, client.Exists("/ns/a1"))
+
+	results, _ := teamA.GetPrefixKey("/ns/")
+	assert.Equal(t, "1", results["/ns/a1"].Value)
+
+	assert.Eventually(t, func() bool {
+		value, _ := watchResult.Load("/ns/a1")
+		return value == "1"
+	}, time.Second, 10*time.Millisecond)
+
+	// 租约
+	leaseID, _ := teamA.LeaseGrant(5)
+	_, _ = teamA.PutLease("/ns/lease", "1", leaseID)
+	info, _ := teamA.LeaseInfo(leaseID)
+	assert.Equal(t, []string{"/ns/lease"}, info.Keys)
+	_, _ = teamA.LeaseRevoke(leaseID)
+
+	// 锁
+	unLock, err := teamA.Lock("/ns/lock", 3)
+	assert.NoError(t, err)
+	unLock()
+
+	// 嵌套
+	sub := teamA.WithNamespace("/sub")
+	_, _ = sub.Put("/a2", "2")
+	result, _ = client.Get("/teamA/sub/a2")
+	assert.Equal(t, "2", result.Value)
+	sub.Close()
+
+	// 关闭命名空间客户端，不影响原客户端
+	_, _ = teamA.DeletePrefixKey("/")
+	teamA.Close()
+	_, err = client.Put("/ns/a1", "1")
+	assert.NoError(t, err)
+	_, _ = client.Delete("/ns/a1")
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+	_, _ = client.DeletePrefixKey("/queue/1/")
+
+	queue := etcd.NewQueue(client, "/queue/1", 1, 2)
+	defer queue.Close()
+	_, _ = queue.Enqueue("a")
+	_, _ = queue.Enqueue("b")
+	_, _ = queue.Enqueue("c", 10)
+
+	count, _ := queue.Len()
+	assert.Equal(t, int64(3), count)
+
+	// 优先级高的先出队
+	item, err := queue.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "c", item.Value)
+	assert.NoError(t, queue.Ack(item))
+
+	// 同优先级按入队顺序
+	item, _ = queue.Dequeue(context.Background())
+	assert.Equal(t, "a", item.Value)
+	assert.Equal(t, 1, item.Attempts)
+	assert.NoError(t, queue.Nack(item))
+
+	// Nack后重新投递
+	item, _ = queue.Dequeue(context.Background())
+	assert.Equal(t, "a", item.Value)
+	assert.Equal(t, 2, item.Attempts)
+
+	stats, _ := queue.Stats()
+	assert.Equal(t, int64(1), stats.Ready)
+	assert.Equal(t, int64(1), stats.Inflight)
+
+	// 超过最大投递次数，移到死信
+	assert.NoError(t, queue.Nack(item))
+	deadLetters, _ := queue.DeadLetters()
+	assert.Len(t, deadLetters, 1)
+	assert.Equal(t, "a", deadLetters[0].Value)
+
+	// 可见性超时后，重新投递
+	item, _ = queue.Dequeue(context.Background())
+	assert.Equal(t, "b", item.Value)
+	time.Sleep(2500 * time.Millisecond)
+	assert.ErrorIs(t, queue.Ack(item), etcd.ErrQueueItemExpired)
+	item, _ = queue.TryDequeue()
+	assert.Equal(t, "b", item.Value)
+	assert.Equal(t, 2, item.Attempts)
+	assert.NoError(t, queue.Ack(item))
+
+	// 队列为空时阻塞等待
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_, _ = queue.Enqueue("d")
+	}()
+	item, _ = queue.Dequeue(context.Background())
+	assert.Equal(t, "d", item.Value)
+	assert.NoError(t, queue.Ack(item))
+
+	item, err = queue.TryDequeue()
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "etcd.record")
+	recorder, err := etcd.NewRecorder(path)
+	assert.NoError(t, err)
+
+	client := recorder.Wrap(container.Resolve[etcd.IClient]("default"))
+	defer client.Close()
+	_, _ = client.DeletePrefixKey("/record/")
+
+	_, _ = client.Put("/record/a1", "hello")
+	_, _ = client.Get("/record/a1")
+	_, _ = client.Get("/record/a2")
+	_, _ = client.Incr("/record/count", 2)
+	_, _ = client.Put("/record/num", "5")
+	_, _ = client.Incr("/record/num", 1)
+	leaseId, _ := client.LeaseGrant(10, "/record/a1")
+	_, _ = client.PutLease("/record/a3", "world", leaseId)
+	_ = client.LeaseKeepAliveOnce(leaseId)
+	unLock, _ := client.Lock("/record/lock", 5)
+	unLock()
+
+	// 并发的锁：第二个Lock等待第一个UnLock（回放时不能死锁）
+	unLock, _ = client.Lock("/record/lock", 5)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		unLock()
+	}()
+	unLock, _ = client.Lock("/record/lock", 5)
+	unLock()
+	_, _ = client.WithNamespace("/record/ns/").Put("b1", "b")
+	_, _ = client.LeaseRevoke(leaseId)
+	assert.False(t, client.Exists("/record/a3"))
+	_, _ = client.GetPrefixKey("/record/")
+	assert.NoError(t, recorder.Close())
+	assert.Equal(t, 20, recorder.Count())
+
+	// 回放到新的内存etcd，结果与录制时一致
+	memory := etcd.NewMemoryEtcd()
+	defer memory.Close()
+	replayClient, _ := memory.Client()
+	defer replayClient.Close()
+
+	result, err := etcd.ReplayFile(context.Background(), replayClient, path, etcd.ReplayOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, result.Total)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Equal(t, 0, result.Mismatch)
+	assert.Equal(t, 2, result.Ops["Get"].Count)
+	assert.Equal(t, 3, result.Ops["Lock"].Count)
+	assert.Equal(t, 0, result.Errors)
+
+	// 写入的值为相同大小的生成值，租约撤销后/record/a1被删除
+	assert.False(t, replayClient.Exists("/record/a1"))
+	kv, _ := replayClient.Get("/record/ns/b1")
+	assert.Equal(t, "x", kv.Value)
+	kv, _ = replayClient.Get("/record/count")
+	assert.Equal(t, "2", kv.Value)
+
+	// 整数值按录制的值回放，之后的Incr才能成功
+	kv, _ = replayClient.Get("/record/num")
+	assert.Equal(t, "6", kv.Value)
+}
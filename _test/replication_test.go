@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReplicate(t *testing.T) {
+	src := container.Resolve[etcd.IClient]("default")
+	dst := container.Resolve[etcd.IClient]("cache")
+	defer src.Close()
+	defer dst.Close()
+
+	_, _ = src.Put("/replication/src/a1", "1")
+	_, _ = dst.Put("/replication/dst/old", "0")
+	config := etcd.ReplicationConfig{
+		Prefixes:      []string{"/replication/src/"},
+		Rewrites:      []etcd.ReplicationRule{{From: "/replication/src/", To: "/replication/dst/"}},
+		CheckpointKey: "/replication/checkpoint",
+	}
+
+	// 全量复制（删除目标中多出来的KEY）
+	replicator, err := etcd.Replicate(src, dst, config)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return !dst.Exists("/replication/dst/old") && dst.Exists("/replication/dst/a1") }, 5*time.Second, 100*time.Millisecond)
+
+	// 持续复制
+	_, _ = src.Put("/replication/src/a2", "2")
+	_, _ = src.Delete("/replication/src/a1")
+	assert.Eventually(t, func() bool { return dst.Exists("/replication/dst/a2") && !dst.Exists("/replication/dst/a1") }, 5*time.Second, 100*time.Millisecond)
+	stats := replicator.Stats()
+	assert.Equal(t, int64(1), stats.FullSyncs)
+	assert.Equal(t, int64(2), stats.Events)
+	replicator.Close()
+
+	// 停止期间的变化，重启后从检查点继续（不再全量复制）
+	header, _ := src.Put("/replication/src/a3", "3")
+	replicator, err = etcd.Replicate(src, dst, config)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return dst.Exists("/replication/dst/a3") }, 5*time.Second, 100*time.Millisecond)
+	assert.Eventually(t, func() bool { return replicator.Stats().Revision >= header.Revision }, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int64(0), replicator.Stats().FullSyncs)
+	replicator.Close()
+
+	_, err = etcd.Replicate(src, dst, etcd.ReplicationConfig{})
+	assert.Error(t, err)
+	_, _ = src.DeletePrefixKey("/replication/")
+}
+
+func TestReplicateCompacted(t *testing.T) {
+	// 压缩会影响其它测试，使用内存etcd
+	memory := etcd.NewMemoryEtcd()
+	defer memory.Close()
+	client, _ := memory.Client()
+	defer client.Close()
+
+	config := etcd.ReplicationConfig{
+		Prefixes:      []string{"/replication/src/"},
+		Rewrites:      []etcd.ReplicationRule{{From: "/replication/src/", To: "/replication/dst/"}},
+		CheckpointKey: "/replication/checkpoint",
+	}
+	_, _ = client.Put("/replication/src/a1", "1")
+	replicator, err := etcd.Replicate(client, client, config)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return client.Exists("/replication/dst/a1") }, 5*time.Second, 100*time.Millisecond)
+	replicator.Close()
+
+	// 停止期间源被压缩到检查点之后，重启后检查点失效，重新全量复制
+	_, _ = client.Delete("/replication/src/a1")
+	header, _ := client.Put("/replication/src/a2", "2")
+	assert.NoError(t, etcd.NewMaintenance(client).Compact(context.Background(), header.Revision, true))
+
+	replicator, err = etcd.Replicate(client, client, config)
+	assert.NoError(t, err)
+	defer replicator.Close()
+	assert.Eventually(t, func() bool {
+		return client.Exists("/replication/dst/a2") && !client.Exists("/replication/dst/a1")
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int64(1), replicator.Stats().FullSyncs)
+
+	// 全量复制后继续监听
+	_, _ = client.Put("/replication/src/a3", "3")
+	assert.Eventually(t, func() bool { return client.Exists("/replication/dst/a3") }, 5*time.Second, 100*time.Millisecond)
+}
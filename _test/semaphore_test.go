@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	semaphore1 := etcd.NewSemaphore(client, "/semaphore/1", 3)
+	semaphore2 := etcd.NewSemaphore(client, "/semaphore/1", 3)
+	defer semaphore1.Close()
+	defer semaphore2.Close()
+
+	assert.NoError(t, semaphore1.Acquire(context.Background(), 2))
+
+	// 剩余1个许可
+	ok, err := semaphore2.TryAcquire(2)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, _ = semaphore2.TryAcquire(1)
+	assert.True(t, ok)
+
+	holders, _ := semaphore1.Holders()
+	assert.Len(t, holders, 2)
+	assert.Equal(t, 2, holders[0].Permits)
+	assert.Equal(t, 1, holders[1].Permits)
+
+	// 许可不足时，等待超时
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, semaphore2.Acquire(ctx, 1), context.DeadlineExceeded)
+
+	// 释放后，排队的客户端获取到许可
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_ = semaphore1.Release(2)
+	}()
+	assert.NoError(t, semaphore2.Acquire(context.Background(), 2))
+
+	holders, _ = semaphore1.Holders()
+	assert.Len(t, holders, 2)
+	assert.NoError(t, semaphore2.Release(3))
+	assert.Error(t, semaphore2.Release(1))
+}
+
+func TestSemaphorePartialRelease(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	semaphore1 := etcd.NewSemaphore(client, "/semaphore/2", 3)
+	semaphore2 := etcd.NewSemaphore(client, "/semaphore/2", 3)
+	defer semaphore1.Close()
+	defer semaphore2.Close()
+
+	assert.NoError(t, semaphore1.Acquire(context.Background(), 3))
+
+	// 部分释放（只减少许可数，不删除KEY），也要唤醒排队的客户端
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		_ = semaphore1.Release(1)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	assert.NoError(t, semaphore2.Acquire(ctx, 1))
+}
+
+func TestSemaphoreSessionExpired(t *testing.T) {
+	client := container.Resolve[etcd.IClient]("default")
+	defer client.Close()
+
+	semaphore := etcd.NewSemaphore(client, "/semaphore/3", 1)
+	defer semaphore.Close()
+
+	assert.NoError(t, semaphore.Acquire(context.Background(), 1))
+	holders, _ := semaphore.Holders()
+	assert.Len(t, holders, 1)
+
+	// 租约被撤销（模拟过期）后，重新创建会话，仍然可以获取许可
+	lease := holders[0].Lease
+	_, err := client.LeaseRevoke(lease)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		ok, _ := semaphore.TryAcquire(1)
+		return ok
+	}, 10*time.Second, 200*time.Millisecond)
+
+	holders, _ = semaphore.Holders()
+	assert.Len(t, holders, 1)
+	assert.NotEqual(t, lease, holders[0].Lease)
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/etcd"
+	"github.com/stretchr/testify/assert"
+)
+
+// 生成自签名的证书、私钥（PEM）
+func newTestCertificate(t *testing.T) (string, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "etcd-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	assert.NoError(t, err)
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(cert), string(key)
+}
+
+func TestTLSConfig(t *testing.T) {
+	cert, key := newTestCertificate(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, []byte(cert), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, []byte(key), 0600))
+
+	// 节点不可达，只校验TLS配置
+	server := "Server=127.0.0.1:23790,DialTimeout=200,"
+	diagnose := func(config string) error {
+		_, err := etcd.Diagnose(server+config, 200*time.Millisecond)
+		return err
+	}
+
+	// 证书文件
+	assert.NoError(t, diagnose("CaFile="+certFile+",CertFile="+certFile+",KeyFile="+keyFile))
+	// 内联的PEM（使用\n代替换行）、base64编码的PEM
+	inlineKey := strings.ReplaceAll(key, "\n", `\n`)
+	assert.NoError(t, diagnose("Ca="+base64.StdEncoding.EncodeToString([]byte(cert))+",Cert="+base64.StdEncoding.EncodeToString([]byte(cert))+",Key="+inlineKey))
+
+	// 只配置了私钥时，同样开启TLS并校验
+	assert.ErrorContains(t, diagnose("Key="+inlineKey), "同时配置")
+	assert.ErrorContains(t, diagnose("KeyFile="+keyFile), "同时配置")
+
+	// 文件不存在
+	assert.ErrorContains(t, diagnose("CaFile="+filepath.Join(dir, "none.pem")), "CaFile")
+	assert.ErrorContains(t, diagnose("CertFile="+certFile+",KeyFile="+filepath.Join(dir, "none.pem")), "KeyFile")
+
+	// 内联的内容不是PEM或base64
+	assert.ErrorContains(t, diagnose("Ca=not-a-pem"), "base64")
+	// 证书与私钥不匹配
+	_, otherKey := newTestCertificate(t)
+	assert.Error(t, diagnose("Cert="+base64.StdEncoding.EncodeToString([]byte(cert))+",Key="+base64.StdEncoding.EncodeToString([]byte(otherKey))))
+}
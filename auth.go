@@ -0,0 +1,232 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/farseer-go/fs/trace"
+	"go.etcd.io/etcd/api/v3/authpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	PermRead      = "READ"      // 读权限
+	PermWrite     = "WRITE"     // 写权限
+	PermReadWrite = "READWRITE" // 读写权限
+)
+
+// User 用户
+type User struct {
+	Name  string   // 用户名
+	Roles []string // 拥有的角色
+}
+
+// Role 角色
+type Role struct {
+	Name        string       // 角色名
+	Permissions []Permission // 拥有的权限
+}
+
+// Permission 对KEY（范围、前缀）的权限
+type Permission struct {
+	Key      string // KEY（或范围的开始）
+	RangeEnd string // 范围的结束（不包含），为空时只针对Key
+	IsPrefix bool   // 针对Key前缀（忽略RangeEnd）
+	Type     string // 权限类型：READ、WRITE、READWRITE（不区分大小写）
+}
+
+// 实际的范围结束
+func (receiver Permission) rangeEnd() string {
+	if receiver.IsPrefix {
+		return etcdV3.GetPrefixRangeEnd(receiver.Key)
+	}
+	return receiver.RangeEnd
+}
+
+func (receiver Permission) String() string {
+	if receiver.IsPrefix {
+		return fmt.Sprintf("%s %s*", receiver.Type, receiver.Key)
+	}
+	if receiver.RangeEnd != "" {
+		return fmt.Sprintf("%s [%s, %s)", receiver.Type, receiver.Key, receiver.RangeEnd)
+	}
+	return fmt.Sprintf("%s %s", receiver.Type, receiver.Key)
+}
+
+type auth struct {
+	etcdCli      *etcdClient // 原始连接（不带KEY前缀）
+	traceManager trace.IManager
+}
+
+// NewAuth 认证与权限（RBAC）管理（不受命名空间影响）
+func NewAuth(client IClient) IAuth {
+	cli := clientOf(client)
+	return &auth{etcdCli: cli.connection(), traceManager: cli.traceManager}
+}
+
+func (receiver *auth) AuthEnable(ctx context.Context) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AuthEnable", "", 0)
+	_, err := receiver.etcdCli.AuthEnable(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) AuthDisable(ctx context.Context) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AuthDisable", "", 0)
+	_, err := receiver.etcdCli.AuthDisable(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) AuthStatus(ctx context.Context) (bool, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AuthStatus", "", 0)
+	rsp, err := receiver.etcdCli.AuthStatus(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return false, err
+	}
+	return rsp.Enabled, nil
+}
+
+func (receiver *auth) UserAdd(ctx context.Context, name string, password string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserAdd", name, 0)
+	_, err := receiver.etcdCli.UserAdd(ctx, name, password)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) UserDelete(ctx context.Context, name string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserDelete", name, 0)
+	_, err := receiver.etcdCli.UserDelete(ctx, name)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) UserChangePassword(ctx context.Context, name string, password string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserChangePassword", name, 0)
+	_, err := receiver.etcdCli.UserChangePassword(ctx, name, password)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) UserGrantRole(ctx context.Context, name string, role string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserGrantRole", name, 0)
+	_, err := receiver.etcdCli.UserGrantRole(ctx, name, role)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) UserRevokeRole(ctx context.Context, name string, role string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserRevokeRole", name, 0)
+	_, err := receiver.etcdCli.UserRevokeRole(ctx, name, role)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) UserGet(ctx context.Context, name string) (*User, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserGet", name, 0)
+	rsp, err := receiver.etcdCli.UserGet(ctx, name)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	return &User{Name: name, Roles: rsp.Roles}, nil
+}
+
+func (receiver *auth) UserList(ctx context.Context) ([]string, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("UserList", "", 0)
+	rsp, err := receiver.etcdCli.UserList(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Users, nil
+}
+
+func (receiver *auth) RoleAdd(ctx context.Context, name string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleAdd", name, 0)
+	_, err := receiver.etcdCli.RoleAdd(ctx, name)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) RoleDelete(ctx context.Context, name string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleDelete", name, 0)
+	_, err := receiver.etcdCli.RoleDelete(ctx, name)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *auth) RoleGet(ctx context.Context, name string) (*Role, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleGet", name, 0)
+	rsp, err := receiver.etcdCli.RoleGet(ctx, name)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	role := &Role{Name: name}
+	for _, perm := range rsp.Perm {
+		role.Permissions = append(role.Permissions, newPermission(perm))
+	}
+	return role, nil
+}
+
+func (receiver *auth) RoleList(ctx context.Context) ([]string, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleList", "", 0)
+	rsp, err := receiver.etcdCli.RoleList(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Roles, nil
+}
+
+func (receiver *auth) RoleGrantPermission(ctx context.Context, name string, permission Permission) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleGrantPermission", permission.Key, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	permType, err := etcdV3.StrToPermissionType(permission.Type)
+	if err != nil {
+		err = fmt.Errorf("权限类型不正确：%s", permission.Type)
+		return err
+	}
+	_, err = receiver.etcdCli.RoleGrantPermission(ctx, name, permission.Key, permission.rangeEnd(), permType)
+	return err
+}
+
+func (receiver *auth) RoleRevokePermission(ctx context.Context, name string, permission Permission) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("RoleRevokePermission", permission.Key, 0)
+	_, err := receiver.etcdCli.RoleRevokePermission(ctx, name, permission.Key, permission.rangeEnd())
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func newPermission(perm *authpb.Permission) Permission {
+	permission := Permission{
+		Key:      string(perm.Key),
+		RangeEnd: string(perm.RangeEnd),
+		Type:     perm.PermType.String(),
+	}
+	if permission.RangeEnd != "" && permission.RangeEnd == etcdV3.GetPrefixRangeEnd(permission.Key) {
+		permission.IsPrefix, permission.RangeEnd = true, ""
+	}
+	return permission
+}
+
+// 统一格式后的权限（用于比较）
+func (receiver Permission) normalize() Permission {
+	receiver.Type = strings.ToUpper(receiver.Type)
+	if receiver.IsPrefix {
+		receiver.RangeEnd = ""
+	} else if receiver.RangeEnd != "" && receiver.RangeEnd == etcdV3.GetPrefixRangeEnd(receiver.Key) {
+		receiver.IsPrefix, receiver.RangeEnd = true, ""
+	}
+	return receiver
+}
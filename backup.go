@@ -0,0 +1,221 @@
+package etcd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/trace"
+)
+
+// 定时备份的文件名格式：etcd-20060102150405.db
+const backupFilePrefix = "etcd-"
+const backupFileSuffix = ".db"
+const backupTimeLayout = "20060102150405"
+
+// 定时备份默认保留的份数
+const defaultBackupRetain = 7
+
+// BackupInfo 备份结果
+type BackupInfo struct {
+	Path     string        // 备份文件
+	Size     int64         // 文件大小（字节）
+	Sha256   string        // 文件的sha256（同时保存到：Path.sha256）
+	Revision int64         // 快照对应的集群Revision
+	Version  string        // 创建快照的etcd版本（用于恢复时选择对应版本的etcdutl）
+	Duration time.Duration // 耗时
+}
+
+// Backup 将集群的快照保存到文件（同时生成path.sha256校验文件），onProgress：已写入的字节数
+func Backup(ctx context.Context, client IClient, path string, onProgress ...func(written int64)) (*BackupInfo, error) {
+	cli := clientOf(client)
+	traceDetailEtcd := cli.traceManager.TraceEtcd("Backup", path, 0)
+	info, err := backup(ctx, cli.connection(), path, onProgress...)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return info, err
+}
+
+// 先写入临时文件，完成后再改名，避免留下不完整的备份
+func backup(ctx context.Context, etcdCli *etcdClient, path string, onProgress ...func(written int64)) (*BackupInfo, error) {
+	startAt := time.Now()
+	rsp, err := etcdCli.SnapshotWithVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rsp.Snapshot.Close() }()
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	partPath := path + ".part"
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	writer := &progressWriter{writer: io.MultiWriter(file, hash), onProgress: onProgress}
+	size, err := io.Copy(writer, rsp.Snapshot)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(partPath)
+		return nil, fmt.Errorf("备份失败：%s", err.Error())
+	}
+
+	info := &BackupInfo{
+		Path:     path,
+		Size:     size,
+		Sha256:   hex.EncodeToString(hash.Sum(nil)),
+		Version:  rsp.Version,
+		Duration: time.Since(startAt),
+	}
+	if rsp.Header != nil {
+		info.Revision = rsp.Header.Revision
+	}
+	// 与sha256sum的格式一致，可通过：sha256sum -c 校验
+	if err = os.WriteFile(path+".sha256", []byte(info.Sha256+"  "+filepath.Base(path)+"\n"), 0600); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// 写入时回调进度
+type progressWriter struct {
+	writer     io.Writer
+	written    int64
+	onProgress []func(written int64)
+}
+
+func (receiver *progressWriter) Write(p []byte) (int, error) {
+	n, err := receiver.writer.Write(p)
+	receiver.written += int64(n)
+	for _, onProgress := range receiver.onProgress {
+		onProgress(receiver.written)
+	}
+	return n, err
+}
+
+// BackupJob 定时备份
+type BackupJob struct {
+	etcdCli      *etcdClient // 原始连接（不带KEY前缀）
+	traceManager trace.IManager
+	dir          string // 备份目录
+	retain       int    // 保留的份数
+	lock         sync.RWMutex
+	last         *BackupInfo
+	cancel       context.CancelFunc
+}
+
+// NewBackupSchedule 定时备份到dir目录，interval：备份的间隔（必须>0），retain：保留的份数（<=0时默认7）
+func NewBackupSchedule(client IClient, dir string, interval time.Duration, retain int) (*BackupJob, error) {
+	return clientOf(client).backupSchedule(dir, interval, retain)
+}
+
+func (receiver *client) backupSchedule(dir string, interval time.Duration, retain int) (*BackupJob, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("定时备份的间隔必须大于0：%s", interval)
+	}
+	if retain <= 0 {
+		retain = defaultBackupRetain
+	}
+	ctx, cancel := context.WithCancel(receiver.connection().Ctx())
+	job := &BackupJob{
+		etcdCli:      receiver.connection(),
+		traceManager: receiver.traceManager,
+		dir:          dir,
+		retain:       retain,
+		cancel:       cancel,
+	}
+	go job.run(ctx, interval)
+	return job, nil
+}
+
+// Last 最后一次成功的备份
+func (receiver *BackupJob) Last() *BackupInfo {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.last
+}
+
+// Close 停止定时备份
+func (receiver *BackupJob) Close() {
+	receiver.cancel()
+}
+
+func (receiver *BackupJob) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			receiver.backup(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (receiver *BackupJob) backup(ctx context.Context) {
+	path := filepath.Join(receiver.dir, backupFilePrefix+time.Now().Format(backupTimeLayout)+backupFileSuffix)
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("BackupSchedule", path, 0)
+	info, err := backup(ctx, receiver.etcdCli, path)
+	traceDetailEtcd.End(err)
+
+	if err != nil {
+		if ctx.Err() == nil {
+			flog.Warningf("Etcd定时备份失败：%s", err.Error())
+		}
+		return
+	}
+	receiver.lock.Lock()
+	receiver.last = info
+	receiver.lock.Unlock()
+	flog.Infof("Etcd定时备份完成：%s，大小：%d，Revision：%d", info.Path, info.Size, info.Revision)
+
+	receiver.cleanup()
+}
+
+// 删除超出保留份数的旧备份
+func (receiver *BackupJob) cleanup() {
+	entries, err := os.ReadDir(receiver.dir)
+	if err != nil {
+		flog.Warningf("Etcd定时备份读取目录失败：%s", err.Error())
+		return
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), backupFilePrefix) && strings.HasSuffix(entry.Name(), backupFileSuffix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+	// 文件名中的时间可以直接按字符串排序
+	sort.Strings(backups)
+	for len(backups) > receiver.retain {
+		path := filepath.Join(receiver.dir, backups[0])
+		if err = os.Remove(path); err != nil {
+			flog.Warningf("Etcd定时备份删除旧备份失败：%s", err.Error())
+		}
+		_ = os.Remove(path + ".sha256")
+		backups = backups[1:]
+	}
+}
@@ -0,0 +1,230 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/farseer-go/fs/trace"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// 屏障KEY的租约时间（单位s），进程崩溃后，最多在这个时间后自动移除
+const barrierTTL = 10
+
+// ErrBarrierHeld 屏障已被其它客户端设置
+var ErrBarrierHeld = fmt.Errorf("屏障已被设置")
+
+// ErrBarrierFull 进入双重屏障的客户端已满
+var ErrBarrierFull = fmt.Errorf("进入屏障的客户端数量已满")
+
+// Barrier 分布式屏障：Hold设置屏障后，所有Wait的客户端将阻塞，直到Release
+type Barrier struct {
+	etcdCli      *etcdClient
+	traceManager trace.IManager
+	key          string // 屏障KEY
+	lock         sync.Mutex
+	session      *concurrency.Session
+}
+
+// NewBarrier 分布式屏障，name：屏障KEY
+func NewBarrier(client IClient, name string) *Barrier {
+	cli := clientOf(client)
+	return &Barrier{
+		etcdCli:      cli.etcdCli,
+		traceManager: cli.traceManager,
+		key:          name,
+	}
+}
+
+// Hold 设置屏障（屏障KEY带有租约，进程崩溃后自动移除）
+func (receiver *Barrier) Hold() error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("BarrierHold", receiver.key, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.session == nil {
+		if receiver.session, err = concurrency.NewSession(receiver.etcdCli, concurrency.WithTTL(barrierTTL)); err != nil {
+			return err
+		}
+	}
+
+	txnRsp, err := receiver.etcdCli.Txn(todo).
+		If(etcdV3.Compare(etcdV3.CreateRevision(receiver.key), "=", 0)).
+		Then(etcdV3.OpPut(receiver.key, "", etcdV3.WithLease(receiver.session.Lease()))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnRsp.Succeeded {
+		err = ErrBarrierHeld
+	}
+	return err
+}
+
+// Release 移除屏障，所有Wait的客户端将继续执行
+func (receiver *Barrier) Release() error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("BarrierRelease", receiver.key, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.session == nil {
+		_, err = receiver.etcdCli.Delete(todo, receiver.key)
+		return err
+	}
+	// 撤销租约，同时删除屏障KEY
+	err = receiver.session.Close()
+	receiver.session = nil
+	return err
+}
+
+// Wait 等待屏障被移除，未设置屏障时立即返回
+func (receiver *Barrier) Wait(ctx context.Context) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("BarrierWait", receiver.key, 0)
+	rsp, err := receiver.etcdCli.Get(ctx, receiver.key)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil || len(rsp.Kvs) == 0 {
+		return err
+	}
+	err = waitEvent(ctx, receiver.etcdCli, receiver.key, rsp.Header.Revision+1, mvccpb.DELETE)
+	return err
+}
+
+// DoubleBarrier 分布式双重屏障：Enter等待count个客户端全部进入，Leave等待所有客户端全部离开
+type DoubleBarrier struct {
+	etcdCli      *etcdClient
+	traceManager trace.IManager
+	key          string // 屏障KEY前缀
+	count        int    // 参与的客户端数量
+	session      *concurrency.Session
+	myKey        string // 本客户端进入屏障时的KEY
+}
+
+// NewDoubleBarrier 分布式双重屏障，name：屏障KEY前缀，count：参与的客户端数量
+func NewDoubleBarrier(client IClient, name string, count int) *DoubleBarrier {
+	cli := clientOf(client)
+	return &DoubleBarrier{
+		etcdCli:      cli.etcdCli,
+		traceManager: cli.traceManager,
+		key:          strings.TrimSuffix(name, "/"),
+		count:        count,
+	}
+}
+
+// Enter 进入屏障，直到count个客户端全部进入后返回
+func (receiver *DoubleBarrier) Enter(ctx context.Context) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("DoubleBarrierEnter", receiver.key, 0)
+	err := receiver.enter(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return err
+}
+
+func (receiver *DoubleBarrier) enter(ctx context.Context) error {
+	// 先检查已进入的客户端，已满时不再进入
+	waiters, err := receiver.waiters(ctx)
+	if err != nil {
+		return err
+	}
+	if len(waiters.Kvs) >= receiver.count {
+		return ErrBarrierFull
+	}
+
+	if receiver.session == nil {
+		if receiver.session, err = concurrency.NewSession(receiver.etcdCli, concurrency.WithTTL(barrierTTL)); err != nil {
+			return err
+		}
+	}
+	receiver.myKey = fmt.Sprintf("%s/waiters/%x", receiver.key, receiver.session.Lease())
+	putRsp, err := receiver.etcdCli.Put(ctx, receiver.myKey, "", etcdV3.WithLease(receiver.session.Lease()))
+	if err != nil {
+		return err
+	}
+	myRevision := putRsp.Header.Revision
+
+	// 进入后再次检查，最后一个进入的客户端负责通知其它客户端
+	if waiters, err = receiver.waiters(ctx); err != nil {
+		return err
+	}
+	if len(waiters.Kvs) >= receiver.count {
+		lastWaiter := waiters.Kvs[receiver.count-1]
+		if myRevision > lastWaiter.CreateRevision {
+			_, _ = receiver.etcdCli.Delete(ctx, receiver.myKey)
+			return ErrBarrierFull
+		}
+		if myRevision == lastWaiter.CreateRevision {
+			_, err = receiver.etcdCli.Put(ctx, receiver.key+"/ready", "")
+			return err
+		}
+	}
+	return waitEvent(ctx, receiver.etcdCli, receiver.key+"/ready", myRevision, mvccpb.PUT)
+}
+
+// Leave 离开屏障，直到所有客户端全部离开后返回
+func (receiver *DoubleBarrier) Leave(ctx context.Context) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("DoubleBarrierLeave", receiver.key, 0)
+	err := receiver.leave(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err == nil && receiver.session != nil {
+		err = receiver.session.Close()
+		receiver.session = nil
+	}
+	return err
+}
+
+func (receiver *DoubleBarrier) leave(ctx context.Context) error {
+	for {
+		waiters, err := receiver.waiters(ctx)
+		if err != nil || len(waiters.Kvs) == 0 {
+			return err
+		}
+
+		lowest, highest := waiters.Kvs[0], waiters.Kvs[0]
+		for _, kv := range waiters.Kvs {
+			if kv.ModRevision < lowest.ModRevision {
+				lowest = kv
+			}
+			if kv.ModRevision > highest.ModRevision {
+				highest = kv
+			}
+		}
+		isLowest := string(lowest.Key) == receiver.myKey
+
+		// 只剩自己，清理屏障后离开
+		if len(waiters.Kvs) == 1 && isLowest {
+			if _, err = receiver.etcdCli.Delete(ctx, receiver.key+"/ready"); err != nil {
+				return err
+			}
+			_, err = receiver.etcdCli.Delete(ctx, receiver.myKey)
+			return err
+		}
+
+		// 最早的客户端等待最后的客户端离开，其它客户端先删除自己，再等待最早的客户端离开
+		// 客户端崩溃时，租约到期会自动删除其KEY，屏障不会被卡住
+		waitKV := lowest
+		if isLowest {
+			waitKV = highest
+		} else if _, err = receiver.etcdCli.Delete(ctx, receiver.myKey); err != nil {
+			return err
+		}
+		if err = waitEvent(ctx, receiver.etcdCli, string(waitKV.Key), waitKV.ModRevision, mvccpb.DELETE); err != nil {
+			return err
+		}
+	}
+}
+
+// 获取已进入屏障的客户端（按创建版本号排序）
+func (receiver *DoubleBarrier) waiters(ctx context.Context) (*etcdV3.GetResponse, error) {
+	return receiver.etcdCli.Get(ctx, receiver.key+"/waiters", etcdV3.WithPrefix(), etcdV3.WithSort(etcdV3.SortByCreateRevision, etcdV3.SortAscend))
+}
@@ -0,0 +1,213 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/parse"
+	"github.com/farseer-go/fs/snc"
+)
+
+// IValidate 绑定的配置在生效前，会先调用Validate校验
+type IValidate interface {
+	Validate() error
+}
+
+// Binder 将etcd中的配置绑定到结构体，并通过Watch保持最新
+type Binder[T any] struct {
+	client    IClient
+	key       string
+	lock      sync.RWMutex
+	value     T
+	revision  int64 // 当前配置所对应的集群Revision
+	onChanges []func(old T, new T)
+	reload    sync.Mutex
+	cancel    context.CancelFunc
+}
+
+// Bind 将key绑定到结构体T，支持两种存储方式：
+// 1、key的值为JSON
+// 2、key作为前缀，每个字段一个KEY，如：key/Name、key/Db/Port（字段名不区分大小写）
+func Bind[T any](client IClient, key string) (*Binder[T], error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	binder := &Binder[T]{
+		client: client,
+		key:    strings.TrimSuffix(key, "/"),
+		cancel: cancel,
+	}
+
+	value, revision, err := binder.load()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	binder.value, binder.revision = value, revision
+
+	// 从读取时的Revision之后开始监听，不漏掉读取与监听之间的变化
+	watchFrom(client, ctx, binder.key, true, revision+1, func(event WatchEvent) {
+		if event.Kv.Key != binder.key && !strings.HasPrefix(event.Kv.Key, binder.key+"/") {
+			return
+		}
+		binder.refresh(event.Kv.Header.Revision)
+	})
+	return binder, nil
+}
+
+// Get 当前的配置
+func (receiver *Binder[T]) Get() T {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.value
+}
+
+// Revision 当前配置所对应的集群Revision
+func (receiver *Binder[T]) Revision() int64 {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.revision
+}
+
+// OnChange 订阅配置变化（校验通过并生效后通知）
+func (receiver *Binder[T]) OnChange(fn func(old T, new T)) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.onChanges = append(receiver.onChanges, fn)
+}
+
+// Close 停止监听
+func (receiver *Binder[T]) Close() {
+	receiver.cancel()
+}
+
+// 重新加载配置，校验通过后替换并通知订阅者（KEY被删除时，恢复为T的零值，零值同样需要校验）
+func (receiver *Binder[T]) refresh(revision int64) {
+	receiver.reload.Lock()
+	defer receiver.reload.Unlock()
+
+	// 已加载过更新的配置
+	if revision <= receiver.Revision() {
+		return
+	}
+
+	value, loadRevision, err := receiver.load()
+	if errors.Is(err, errBindNotExists) {
+		// KEY已被删除，零值校验通过后才替换旧配置
+		var zero T
+		value, loadRevision, err = zero, revision, validateBind(zero)
+	}
+	if err != nil {
+		flog.Warningf("Etcd配置：%s 重新加载失败，继续使用旧配置：%s", receiver.key, err.Error())
+		return
+	}
+
+	receiver.lock.Lock()
+	old := receiver.value
+	receiver.value, receiver.revision = value, loadRevision
+	onChanges := receiver.onChanges
+	receiver.lock.Unlock()
+
+	for _, onChange := range onChanges {
+		onChange(old, value)
+	}
+}
+
+var errBindNotExists = errors.New("KEY不存在")
+
+// 从etcd读取配置，并校验
+func (receiver *Binder[T]) load() (T, int64, error) {
+	var value T
+	kvs, err := receiver.client.GetPrefixKey(receiver.key)
+	if err != nil {
+		return value, 0, err
+	}
+
+	var revision int64
+	tree := make(map[string]string)
+	for key, kv := range kvs {
+		revision = kv.Header.Revision
+		if key == receiver.key {
+			tree[""] = kv.Value
+		} else if strings.HasPrefix(key, receiver.key+"/") {
+			tree[strings.ToLower(key[len(receiver.key)+1:])] = kv.Value
+		}
+	}
+
+	if jsonValue, isJson := tree[""]; isJson {
+		// 1、JSON
+		if err = snc.Unmarshal([]byte(jsonValue), &value); err != nil {
+			return value, 0, fmt.Errorf("JSON解析失败：%w", err)
+		}
+	} else if len(tree) > 0 {
+		// 2、每个字段一个KEY
+		if err = bindTree(reflect.ValueOf(&value).Elem(), "", tree); err != nil {
+			return value, 0, err
+		}
+	} else {
+		return value, 0, fmt.Errorf("%w：%s", errBindNotExists, receiver.key)
+	}
+
+	if err = validateBind(value); err != nil {
+		return value, 0, err
+	}
+	return value, revision, nil
+}
+
+// 校验配置（T或*T实现了IValidate时）
+func validateBind[T any](value T) error {
+	var err error
+	if validate, isValidate := any(&value).(IValidate); isValidate {
+		err = validate.Validate()
+	} else if validate, isValidate := any(value).(IValidate); isValidate {
+		err = validate.Validate()
+	}
+	if err != nil {
+		return fmt.Errorf("配置校验失败：%w", err)
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// 将每个字段一个KEY的配置，赋值到结构体（path为小写的相对路径）
+func bindTree(val reflect.Value, path string, tree map[string]string) error {
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("按字段绑定时，类型必须为结构体：%s", val.Type().String())
+	}
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := path + strings.ToLower(field.Name)
+		fieldVal := val.Field(i)
+
+		value, exists := tree[fieldPath]
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != timeType && !exists:
+			if err := bindTree(fieldVal, fieldPath+"/", tree); err != nil {
+				return err
+			}
+		case !exists:
+			continue
+		case field.Type.Kind() == reflect.Struct || field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Ptr:
+			// 复杂类型，值为JSON
+			if err := snc.Unmarshal([]byte(value), fieldVal.Addr().Interface()); err != nil {
+				return fmt.Errorf("字段：%s JSON解析失败：%s", field.Name, err.Error())
+			}
+		default:
+			result := reflect.ValueOf(parse.ConvertValue(value, field.Type))
+			if !result.IsValid() || !result.Type().ConvertibleTo(field.Type) {
+				return fmt.Errorf("字段：%s 的值：%s 无法转换为%s", field.Name, value, field.Type.String())
+			}
+			fieldVal.Set(result.Convert(field.Type))
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/farseer-go/fs/configure"
 	"github.com/farseer-go/fs/container"
 	"github.com/farseer-go/fs/core"
+	"github.com/farseer-go/fs/flog"
 	"github.com/farseer-go/fs/trace"
 )
 
@@ -22,43 +23,33 @@ func (c *connectionChecker) Check(configString string) (bool, error) {
 	// 取消链路
 	container.Resolve[trace.IManager]().Ignore()
 
-	if configString == "" {
-		return false, fmt.Errorf("连接字符串不能为空")
-	}
-
-	// 解析配置字符串
+	// 获取连接超时时间
 	config := configure.ParseString[etcdConfig](configString)
+	dialTimeout := time.Duration(config.DialTimeout) * time.Millisecond
 
-	if config.Server == "" {
-		return false, fmt.Errorf("Server配置不正确：%s", configString)
-	}
-
-	// 调用open方法创建客户端
-	cli, err := open(config)
+	// 逐个节点诊断
+	report, err := Diagnose(configString, dialTimeout)
 	if err != nil {
-		return false, fmt.Errorf("连接Etcd失败：%s", err.Error())
+		return false, err
 	}
-
-	defer cli.Close()
-
-	// 获取连接超时时间
-	dialTimeout := time.Duration(config.DialTimeout) * time.Millisecond
-	if dialTimeout == 0 {
-		dialTimeout = 5 * time.Second
+	if report.Reachable() == 0 {
+		return false, fmt.Errorf("Etcd连接测试失败：\n%s", report.String())
 	}
-
-	// 通过Status命令测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
-	defer cancel()
-
-	_, err = cli.(*client).etcdCli.Status(ctx, config.Server)
-	if err != nil {
-		return false, fmt.Errorf("Etcd连接测试失败：%s", err.Error())
+	if !report.Healthy() {
+		flog.Warningf("Etcd集群存在异常：\n%s", report.String())
 	}
 
 	return true, nil
 }
 
+// Diagnose 诊断集群的每个节点（可通过类型断言从IConnectionChecker中使用）
+func (c *connectionChecker) Diagnose(configString string) (*DiagnosticReport, error) {
+	container.Resolve[trace.IManager]().Ignore()
+
+	config := configure.ParseString[etcdConfig](configString)
+	return Diagnose(configString, time.Duration(config.DialTimeout)*time.Millisecond)
+}
+
 // CheckWithTimeout 带超时时间的连接检查
 // 实现IConnectionChecker接口，参数为 time.Duration
 // CheckWithTimeout 带超时时间的连接检查
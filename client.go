@@ -12,6 +12,7 @@ import (
 	"github.com/farseer-go/fs/trace"
 	etcdV3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
+	"google.golang.org/grpc"
 )
 
 var todo = context.TODO()
@@ -24,11 +25,59 @@ type LeaseID int64
 
 type client struct {
 	etcdCli      *etcdClient
+	conn         *etcdClient // 原始连接（开启命名空间时才有值，此时etcdCli为带前缀的客户端）
+	namespace    string      // KEY前缀
+	isView       bool        // 通过WithNamespace创建，与父客户端共用连接（Close时不关闭连接）
 	traceManager trace.IManager
+	getCache     *getCache          // Get的本地缓存（未开启时为nil）
+	monitor      *connectionMonitor // 连接监控（与命名空间客户端共用）
+	memoryConn   *grpc.ClientConn   // 内存客户端的连接（与命名空间客户端共用），其它客户端为nil
+}
+
+// 包装了其它客户端的IClient（连接池引用、故障注入、录制）
+type wrappedClient interface {
+	unwrap() IClient
+}
+
+// 取出底层的客户端，用于创建信号量、队列、运维等（直接使用底层的连接，不经过故障注入、录制）
+// 故障转移客户端取创建时正在使用的集群；不是本包实现的IClient，使用Original()的连接
+func clientOf(c IClient) *client {
+	for {
+		switch cli := c.(type) {
+		case *client:
+			return cli
+		case *failoverClient:
+			c = cli.active()
+		case wrappedClient:
+			c = cli.unwrap()
+		default:
+			return &client{
+				etcdCli:      c.Original(),
+				namespace:    c.Namespace(),
+				isView:       true,
+				traceManager: container.Resolve[trace.IManager](),
+				monitor:      newConnectionMonitor(nil, 0),
+			}
+		}
+	}
+}
+
+// 创建客户端（配置了备用集群时，创建主备集群的故障转移客户端）
+func openClient(config etcdConfig) (IClient, error) {
+	if config.StandbyServer != "" {
+		return openFailover(config)
+	}
+	return open(config)
 }
 
 // 创建客户端
 func open(config etcdConfig) (IClient, error) {
+	monitorInterval := time.Duration(config.MonitorInterval) * time.Millisecond
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return &client{traceManager: container.Resolve[trace.IManager](), monitor: newConnectionMonitor(nil, monitorInterval)}, err
+	}
+
 	cli, err := etcdV3.New(etcdV3.Config{
 		Endpoints:            strings.Split(config.Server, "|"),
 		DialTimeout:          time.Duration(config.DialTimeout) * time.Millisecond,
@@ -40,16 +89,29 @@ func open(config etcdConfig) (IClient, error) {
 		Password:             config.Password,
 		RejectOldCluster:     config.RejectOldCluster,
 		PermitWithoutStream:  config.PermitWithoutStream,
+		TLS:                  tlsConfig,
 	})
-	return &client{
+	c := &client{
 		etcdCli:      cli,
 		traceManager: container.Resolve[trace.IManager](),
-	}, err
+		monitor:      newConnectionMonitor(cli, monitorInterval),
+	}
+	if err != nil {
+		return c, err
+	}
+	if config.Namespace != "" {
+		c.etcdCli, c.conn, c.namespace = newNamespaceClient(cli, nil, config.Namespace), cli, config.Namespace
+	}
+	if config.CacheSize > 0 {
+		c.getCache = newGetCache(c.etcdCli, config.CacheSize, time.Duration(config.CacheTTL)*time.Millisecond)
+	}
+	return c, nil
 }
 
 func (receiver *client) Put(key, value string) (*Header, error) {
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("Put", key, 0)
 	rsp, err := receiver.etcdCli.Put(todo, key, value)
+	receiver.invalidate(key)
 	defer func() { traceDetailEtcd.End(err) }()
 
 	if err != nil {
@@ -61,6 +123,7 @@ func (receiver *client) Put(key, value string) (*Header, error) {
 func (receiver *client) PutLease(key, value string, leaseId LeaseID) (*Header, error) {
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("PutLease", key, int64(leaseId))
 	rsp, err := receiver.etcdCli.Put(todo, key, value, etcdV3.WithLease(etcdV3.LeaseID(leaseId)))
+	receiver.invalidate(key)
 	defer func() { traceDetailEtcd.End(err) }()
 
 	if err != nil {
@@ -80,6 +143,13 @@ func (receiver *client) PutJsonLease(key string, data any, leaseId LeaseID) (*He
 }
 
 func (receiver *client) Get(key string) (*KeyValue, error) {
+	// 优先读取本地缓存
+	if receiver.getCache != nil {
+		if result, exists := receiver.getCache.get(key); exists {
+			return result, nil
+		}
+	}
+
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("Get", key, 0)
 
 	var result *KeyValue
@@ -94,6 +164,10 @@ func (receiver *client) Get(key string) (*KeyValue, error) {
 	} else {
 		result = &KeyValue{Header: newResponse(rsp.Header), Key: key}
 	}
+	if receiver.getCache != nil {
+		cacheValue := *result
+		receiver.getCache.set(key, &cacheValue)
+	}
 	return result, err
 }
 
@@ -130,6 +204,7 @@ func (receiver *client) Delete(key string) (*Header, error) {
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("Delete", key, 0)
 
 	rsp, err := receiver.etcdCli.Delete(todo, key)
+	receiver.invalidate(key)
 	defer func() { traceDetailEtcd.End(err) }()
 
 	if err != nil {
@@ -142,6 +217,7 @@ func (receiver *client) DeletePrefixKey(prefixKey string) (*Header, error) {
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("DeletePrefixKey", prefixKey, 0)
 
 	rsp, err := receiver.etcdCli.Delete(todo, prefixKey, etcdV3.WithPrefix())
+	receiver.invalidatePrefix(prefixKey)
 	defer func() { traceDetailEtcd.End(err) }()
 
 	if err != nil {
@@ -151,20 +227,49 @@ func (receiver *client) DeletePrefixKey(prefixKey string) (*Header, error) {
 }
 
 func (receiver *client) Watch(ctx context.Context, key string, watchFunc func(event WatchEvent)) {
-	watch := receiver.etcdCli.Watch(ctx, key)
+	receiver.watchFrom(ctx, key, false, 0, watchFunc)
+}
+
+func (receiver *client) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
+	receiver.watchFrom(ctx, prefixKey, true, 0, watchFunc)
+}
+
+// 从revision开始监听（revision<=0时从当前开始），isPrefix：按前缀监听
+func (receiver *client) watchFrom(ctx context.Context, key string, isPrefix bool, revision int64, watchFunc func(event WatchEvent)) {
+	var opts []etcdV3.OpOption
+	if isPrefix {
+		opts = append(opts, etcdV3.WithPrefix())
+	}
+	if revision > 0 {
+		opts = append(opts, etcdV3.WithRev(revision))
+	}
+	watch := receiver.etcdCli.Watch(ctx, key, opts...)
 	// 异步处理
 	go func() {
 		// InitContext 初始化同一协程上下文，避免在同一协程中多次初始化
 		asyncLocal.InitContext()
-		for response := range watch {
-			for _, event := range response.Events {
-				watchEvent := WatchEvent{
-					Type: event.Type.String(),
-					Kv:   newValue(event.Kv, &response.Header),
+		for watch != nil {
+			var compactRevision int64
+			for response := range watch {
+				if response.CompactRevision > 0 {
+					compactRevision = response.CompactRevision
+				}
+				for _, event := range response.Events {
+					watchEvent := WatchEvent{
+						Type: event.Type.String(),
+						Kv:   newValue(event.Kv, &response.Header),
+					}
+					entryWatchKey := receiver.traceManager.EntryWatchKey(key)
+					watchFunc(watchEvent)
+					container.Resolve[trace.IManager]().Push(entryWatchKey, nil)
 				}
-				entryWatchKey := receiver.traceManager.EntryWatchKey(key)
-				watchFunc(watchEvent)
-				container.Resolve[trace.IManager]().Push(entryWatchKey, nil)
+			}
+			watch = nil
+
+			// 开始监听的revision已被压缩，从压缩后最早的revision继续监听（被压缩的变化无法再收到）
+			if compactRevision > 0 && ctx.Err() == nil {
+				flog.Warningf("Etcd监听：%s 的revision已被压缩，从%d继续监听", key, compactRevision)
+				watch = receiver.etcdCli.Watch(ctx, key, append(opts, etcdV3.WithRev(compactRevision))...)
 			}
 		}
 		flog.Info("退出了")
@@ -172,26 +277,28 @@ func (receiver *client) Watch(ctx context.Context, key string, watchFunc func(ev
 	}()
 }
 
-func (receiver *client) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
-	watch := receiver.etcdCli.Watch(ctx, prefixKey, etcdV3.WithPrefix())
-	// 异步处理
-	go func() {
-		// InitContext 初始化同一协程上下文，避免在同一协程中多次初始化
-		asyncLocal.InitContext()
-		for response := range watch {
-			for _, event := range response.Events {
-				watchEvent := WatchEvent{
-					Type: event.Type.String(),
-					Kv:   newValue(event.Kv, &response.Header),
-				}
-				entryWatchKey := receiver.traceManager.EntryWatchKey(prefixKey)
-				watchFunc(watchEvent)
-				container.Resolve[trace.IManager]().Push(entryWatchKey, nil)
+// 从revision开始监听key（revision<=0时从当前开始），用于先读取、再从读取时的Revision之后监听，不漏掉中间的变化
+// 被包装的客户端（连接池引用、故障注入、录制）使用被包装的客户端监听；其它IClient的实现不支持指定revision，从当前开始监听
+func watchFrom(c IClient, ctx context.Context, key string, isPrefix bool, revision int64, watchFunc func(event WatchEvent)) {
+	for {
+		switch cli := c.(type) {
+		case *client:
+			cli.watchFrom(ctx, key, isPrefix, revision, watchFunc)
+			return
+		case *failoverClient:
+			cli.watch(ctx, key, isPrefix, revision, watchFunc)
+			return
+		case wrappedClient:
+			c = cli.unwrap()
+		default:
+			if isPrefix {
+				c.WatchPrefixKey(ctx, key, watchFunc)
+			} else {
+				c.Watch(ctx, key, watchFunc)
 			}
+			return
 		}
-		flog.Info("退出了")
-		asyncLocal.Release()
-	}()
+	}
 }
 
 func (receiver *client) Original() *etcdClient {
@@ -199,7 +306,36 @@ func (receiver *client) Original() *etcdClient {
 }
 
 func (receiver *client) Close() {
+	if receiver.getCache != nil {
+		receiver.getCache.clear()
+	}
 	_ = receiver.etcdCli.Close()
+	if receiver.conn != nil && !receiver.isView {
+		_ = receiver.conn.Close()
+	}
+}
+
+// GetCacheStats Get本地缓存的统计（需配置CacheSize开启）
+func GetCacheStats(client IClient) CacheStats {
+	cli := clientOf(client)
+	if cli.getCache == nil {
+		return CacheStats{}
+	}
+	return cli.getCache.stats()
+}
+
+// 本地修改KEY后，立即让Get的本地缓存失效（不等待Watch通知）
+func (receiver *client) invalidate(key string) {
+	if receiver.getCache != nil {
+		receiver.getCache.remove(key)
+	}
+}
+
+// 根据KEY前缀删除本地缓存
+func (receiver *client) invalidatePrefix(prefixKey string) {
+	if receiver.getCache != nil {
+		receiver.getCache.removePrefix(prefixKey)
+	}
 }
 
 func (receiver *client) LeaseGrant(ttl int64, keys ...string) (LeaseID, error) {
@@ -279,7 +415,7 @@ func (receiver *client) LeaseRevoke(leaseId LeaseID) (*Header, error) {
 func (receiver *client) LeaseInfo(leaseId LeaseID) (*LeaseInfo, error) {
 	traceDetailEtcd := receiver.traceManager.TraceEtcd("LeaseInfo", "", int64(leaseId))
 
-	leaseTimeToLiveResponse, err := receiver.etcdCli.TimeToLive(todo, etcdV3.LeaseID(leaseId))
+	leaseTimeToLiveResponse, err := receiver.etcdCli.TimeToLive(todo, etcdV3.LeaseID(leaseId), etcdV3.WithAttachedKeys())
 	defer func() { traceDetailEtcd.End(err) }()
 
 	if err != nil {
@@ -0,0 +1,259 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// 所有配置的客户端（key：IOC别名）
+var clientPools = make(map[string]*clientPool)
+var clientPoolsLock sync.Mutex
+
+// 同一个配置名称共用一个客户端（连接），首次使用时才连接
+// 连接保持到重新注册、Unregister或应用关闭时才关闭，取出的客户端Close时不会关闭连接
+type clientPool struct {
+	open   func() (IClient, error) // 创建连接
+	lock   sync.Mutex
+	client IClient
+}
+
+// 客户端的引用，Close后不能再使用（不会关闭共用的连接）
+type clientRef struct {
+	inner    IClient
+	isClosed int32
+}
+
+var errClientClosed = errors.New("客户端已关闭")
+
+// 注册配置，返回客户端池
+func newClientPool(name string, open func() (IClient, error)) *clientPool {
+	clientPoolsLock.Lock()
+	defer clientPoolsLock.Unlock()
+
+	// 重新注册时，关闭原来的连接
+	if pool, exists := clientPools[name]; exists {
+		pool.lock.Lock()
+		pool.closeClient()
+		pool.lock.Unlock()
+	}
+	pool := &clientPool{open: open}
+	clientPools[name] = pool
+	return pool
+}
+
+// 移除配置，并关闭连接
+func removeClientPool(name string) {
+	clientPoolsLock.Lock()
+	defer clientPoolsLock.Unlock()
+
+	if pool, exists := clientPools[name]; exists {
+		pool.lock.Lock()
+		pool.closeClient()
+		pool.lock.Unlock()
+		delete(clientPools, name)
+	}
+}
+
+// 获取客户端的引用（未连接时先连接）
+func (receiver *clientPool) acquire() (IClient, error) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.client == nil {
+		cli, err := receiver.open()
+		if err != nil {
+			return cli, err
+		}
+		receiver.client = cli
+	}
+	return &clientRef{inner: receiver.client}, nil
+}
+
+// 需在加锁后调用
+func (receiver *clientPool) closeClient() {
+	if receiver.client != nil {
+		receiver.client.Close()
+		receiver.client = nil
+	}
+}
+
+// closeAll 关闭所有的客户端（应用关闭时调用）
+func closeAll() {
+	clientPoolsLock.Lock()
+	defer clientPoolsLock.Unlock()
+
+	for _, pool := range clientPools {
+		pool.lock.Lock()
+		pool.closeClient()
+		pool.lock.Unlock()
+	}
+}
+
+// Close 释放引用（不会关闭共用的连接），之后的请求都返回错误，重复调用无效
+func (receiver *clientRef) Close() {
+	atomic.StoreInt32(&receiver.isClosed, 1)
+}
+
+// 引用已释放时返回错误
+func (receiver *clientRef) check() error {
+	if atomic.LoadInt32(&receiver.isClosed) == 1 {
+		return errClientClosed
+	}
+	return nil
+}
+
+// 引用的客户端
+func (receiver *clientRef) unwrap() IClient {
+	return receiver.inner
+}
+
+func (receiver *clientRef) Put(key, value string) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Put(key, value)
+}
+
+func (receiver *clientRef) PutLease(key, value string, leaseId LeaseID) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.PutLease(key, value, leaseId)
+}
+
+func (receiver *clientRef) PutJson(key string, data any) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.PutJson(key, data)
+}
+
+func (receiver *clientRef) PutJsonLease(key string, data any, leaseId LeaseID) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.PutJsonLease(key, data, leaseId)
+}
+
+func (receiver *clientRef) Get(key string) (*KeyValue, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Get(key)
+}
+
+func (receiver *clientRef) GetPrefixKey(prefixKey string) (map[string]*KeyValue, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.GetPrefixKey(prefixKey)
+}
+
+func (receiver *clientRef) Delete(key string) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Delete(key)
+}
+
+func (receiver *clientRef) DeletePrefixKey(prefixKey string) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.DeletePrefixKey(prefixKey)
+}
+
+// Exists 引用已释放时返回false
+func (receiver *clientRef) Exists(key string) bool {
+	if err := receiver.check(); err != nil {
+		return false
+	}
+	return receiver.inner.Exists(key)
+}
+
+func (receiver *clientRef) Incr(key string, delta int64) (int64, error) {
+	if err := receiver.check(); err != nil {
+		return 0, err
+	}
+	return receiver.inner.Incr(key, delta)
+}
+
+func (receiver *clientRef) Decr(key string, delta int64) (int64, error) {
+	if err := receiver.check(); err != nil {
+		return 0, err
+	}
+	return receiver.inner.Decr(key, delta)
+}
+
+// Watch 引用已释放时不再监听
+func (receiver *clientRef) Watch(ctx context.Context, key string, watchFunc func(event WatchEvent)) {
+	if err := receiver.check(); err != nil {
+		return
+	}
+	receiver.inner.Watch(ctx, key, watchFunc)
+}
+
+// WatchPrefixKey 引用已释放时不再监听
+func (receiver *clientRef) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
+	if err := receiver.check(); err != nil {
+		return
+	}
+	receiver.inner.WatchPrefixKey(ctx, prefixKey, watchFunc)
+}
+
+func (receiver *clientRef) LeaseGrant(ttl int64, keys ...string) (LeaseID, error) {
+	if err := receiver.check(); err != nil {
+		return 0, err
+	}
+	return receiver.inner.LeaseGrant(ttl, keys...)
+}
+
+func (receiver *clientRef) LeaseKeepAlive(ctx context.Context, leaseId LeaseID) error {
+	if err := receiver.check(); err != nil {
+		return err
+	}
+	return receiver.inner.LeaseKeepAlive(ctx, leaseId)
+}
+
+func (receiver *clientRef) LeaseKeepAliveOnce(leaseId LeaseID) error {
+	if err := receiver.check(); err != nil {
+		return err
+	}
+	return receiver.inner.LeaseKeepAliveOnce(leaseId)
+}
+
+func (receiver *clientRef) LeaseRevoke(leaseId LeaseID) (*Header, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.LeaseRevoke(leaseId)
+}
+
+func (receiver *clientRef) LeaseInfo(leaseId LeaseID) (*LeaseInfo, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.LeaseInfo(leaseId)
+}
+
+func (receiver *clientRef) Lock(lockKey string, lockTTL int) (UnLock, error) {
+	if err := receiver.check(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Lock(lockKey, lockTTL)
+}
+
+// WithNamespace 命名空间客户端与共用的连接绑定，Close时只关闭自己的Watch、租约
+func (receiver *clientRef) WithNamespace(prefix string) IClient {
+	return receiver.inner.WithNamespace(prefix)
+}
+
+func (receiver *clientRef) Namespace() string {
+	return receiver.inner.Namespace()
+}
+
+func (receiver *clientRef) Original() *etcdClient {
+	return receiver.inner.Original()
+}
@@ -0,0 +1,297 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/trace"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	CompactionPeriodic = "periodic" // 按时间保留历史版本
+	CompactionRevision = "revision" // 按版本数保留历史版本
+)
+
+// 选举的租约时间（单位s），Leader崩溃后，最多在这个时间后由其它实例接管
+const compactorTTL = 10
+
+// revision模式的检测间隔（与etcd服务端的自动压缩一致）
+const compactorRevisionInterval = 5 * time.Minute
+
+// 默认的选举KEY
+const defaultCompactorElection = "/farseer-go/etcd/compactor"
+
+// CompactionConfig 自动压缩的配置
+type CompactionConfig struct {
+	Mode         string // 压缩模式：periodic（按时间保留）、revision（按版本数保留）
+	Retention    string // periodic：保留的时间，如：1h、30m；revision：保留的版本数，如：10000
+	DefragWindow string // 压缩后在这个时间窗口内进行碎片整理，如：02:00-04:00，为空时不整理
+	ElectionKey  string // 多个实例之间选举的KEY（只有Leader执行压缩），默认：/farseer-go/etcd/compactor
+}
+
+// Compactor 自动压缩历史版本（多个实例之间选举，只有Leader执行）
+type Compactor struct {
+	etcdCli       *etcdClient // 原始连接（不带KEY前缀）
+	traceManager  trace.IManager
+	config        CompactionConfig
+	retention     time.Duration // periodic：保留的时间
+	retentionRevs int64         // revision：保留的版本数
+	defragFrom    time.Duration // 碎片整理窗口的开始时间（距0点）
+	defragTo      time.Duration // 碎片整理窗口的结束时间（距0点）
+	lock          sync.RWMutex
+	isLeader      bool
+	lastRevision  int64 // 最后一次压缩到的版本
+	cancel        context.CancelFunc
+}
+
+// 按时间记录的集群版本
+type compactorSample struct {
+	at       time.Time
+	revision int64
+}
+
+// NewCompactor 自动压缩历史版本（多个实例之间选举，只有Leader执行压缩）
+func NewCompactor(client IClient, config CompactionConfig) (*Compactor, error) {
+	return clientOf(client).autoCompaction(config)
+}
+
+func (receiver *client) autoCompaction(config CompactionConfig) (*Compactor, error) {
+	compactor := &Compactor{
+		etcdCli:      receiver.connection(),
+		traceManager: receiver.traceManager,
+		config:       config,
+	}
+	if compactor.config.ElectionKey == "" {
+		compactor.config.ElectionKey = defaultCompactorElection
+	}
+
+	var err error
+	switch config.Mode {
+	case CompactionPeriodic:
+		if compactor.retention, err = time.ParseDuration(config.Retention); err != nil || compactor.retention <= 0 {
+			return nil, fmt.Errorf("自动压缩的保留时间不正确：%s", config.Retention)
+		}
+	case CompactionRevision:
+		if compactor.retentionRevs, err = strconv.ParseInt(config.Retention, 10, 64); err != nil || compactor.retentionRevs <= 0 {
+			return nil, fmt.Errorf("自动压缩的保留版本数不正确：%s", config.Retention)
+		}
+	default:
+		return nil, fmt.Errorf("自动压缩模式不正确：%s", config.Mode)
+	}
+	if config.DefragWindow != "" {
+		if compactor.defragFrom, compactor.defragTo, err = parseDefragWindow(config.DefragWindow); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(compactor.etcdCli.Ctx())
+	compactor.cancel = cancel
+	go compactor.run(ctx)
+	return compactor, nil
+}
+
+// IsLeader 当前实例是否为Leader（负责压缩）
+func (receiver *Compactor) IsLeader() bool {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.isLeader
+}
+
+// LastRevision 最后一次压缩到的版本
+func (receiver *Compactor) LastRevision() int64 {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.lastRevision
+}
+
+// Close 停止压缩（为Leader时会让出）
+func (receiver *Compactor) Close() {
+	receiver.cancel()
+}
+
+// 竞选Leader，成为Leader后执行压缩，会话失效时重新竞选
+func (receiver *Compactor) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := receiver.campaign(ctx); err != nil && ctx.Err() == nil {
+			flog.Warningf("Etcd自动压缩选举失败：%s", err.Error())
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+func (receiver *Compactor) campaign(ctx context.Context) error {
+	session, err := concurrency.NewSession(receiver.etcdCli, concurrency.WithTTL(compactorTTL))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+
+	election := concurrency.NewElection(session, receiver.config.ElectionKey)
+	if err = election.Campaign(ctx, ""); err != nil {
+		return err
+	}
+	flog.Infof("Etcd自动压缩：当前实例成为Leader（%s，保留：%s）", receiver.config.Mode, receiver.config.Retention)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// 会话失效（如网络中断导致租约过期）时，不再是Leader
+		select {
+		case <-session.Done():
+			cancel()
+		case <-leaderCtx.Done():
+		}
+	}()
+
+	receiver.setLeader(true)
+	defer receiver.setLeader(false)
+	receiver.compactLoop(leaderCtx)
+	return nil
+}
+
+func (receiver *Compactor) setLeader(isLeader bool) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.isLeader = isLeader
+}
+
+// 定时检测，达到保留条件时压缩
+func (receiver *Compactor) compactLoop(ctx context.Context) {
+	interval := compactorRevisionInterval
+	if receiver.config.Mode == CompactionPeriodic {
+		// 每1/10的保留时间记录一次版本（最多1小时），压缩到保留时间之前记录的版本
+		interval = receiver.retention / 10
+		if interval > time.Hour {
+			interval = time.Hour
+		}
+		if interval < time.Second {
+			interval = time.Second
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var samples []compactorSample
+	needDefrag := false
+	for {
+		if revision, err := receiver.currentRevision(ctx); err == nil {
+			var target int64
+			if receiver.config.Mode == CompactionPeriodic {
+				samples = append(samples, compactorSample{at: time.Now(), revision: revision})
+				deadline := time.Now().Add(-receiver.retention)
+				for len(samples) > 0 && !samples[0].at.After(deadline) {
+					target = samples[0].revision
+					samples = samples[1:]
+				}
+			} else {
+				target = revision - receiver.retentionRevs
+			}
+
+			if target > receiver.LastRevision() {
+				if err = receiver.compact(ctx, target); err != nil {
+					flog.Warningf("Etcd自动压缩失败：%s", err.Error())
+				} else {
+					needDefrag = receiver.config.DefragWindow != ""
+				}
+			}
+		} else if ctx.Err() == nil {
+			flog.Warningf("Etcd自动压缩获取当前版本失败：%s", err.Error())
+		}
+
+		if needDefrag && receiver.inDefragWindow(time.Now()) {
+			receiver.defragment(ctx)
+			needDefrag = false
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (receiver *Compactor) currentRevision(ctx context.Context) (int64, error) {
+	rsp, err := receiver.etcdCli.Get(ctx, "\x00", etcdV3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return rsp.Header.Revision, nil
+}
+
+func (receiver *Compactor) compact(ctx context.Context, revision int64) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AutoCompact", "", 0)
+	_, err := receiver.etcdCli.Compact(ctx, revision, etcdV3.WithCompactPhysical())
+	defer func() { traceDetailEtcd.End(err) }()
+
+	// 已被压缩过（如其它工具或服务端的自动压缩）
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	receiver.lock.Lock()
+	receiver.lastRevision = revision
+	receiver.lock.Unlock()
+	flog.Infof("Etcd自动压缩：已压缩到版本%d", revision)
+	return nil
+}
+
+// 逐个节点进行碎片整理（避免所有节点同时阻塞）
+func (receiver *Compactor) defragment(ctx context.Context) {
+	for _, endpoint := range receiver.etcdCli.Endpoints() {
+		traceDetailEtcd := receiver.traceManager.TraceEtcd("AutoDefragment", endpoint, 0)
+		_, err := receiver.etcdCli.Defragment(ctx, endpoint)
+		traceDetailEtcd.End(err)
+
+		if err != nil {
+			flog.Warningf("Etcd碎片整理失败：%s：%s", endpoint, err.Error())
+		} else {
+			flog.Infof("Etcd碎片整理完成：%s", endpoint)
+		}
+	}
+}
+
+// 是否在碎片整理的时间窗口内（支持跨0点，如：23:00-02:00）
+func (receiver *Compactor) inDefragWindow(now time.Time) bool {
+	if receiver.config.DefragWindow == "" {
+		return false
+	}
+	clock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	if receiver.defragFrom <= receiver.defragTo {
+		return clock >= receiver.defragFrom && clock < receiver.defragTo
+	}
+	return clock >= receiver.defragFrom || clock < receiver.defragTo
+}
+
+// 解析时间窗口，如：02:00-04:00
+func parseDefragWindow(window string) (time.Duration, time.Duration, error) {
+	times := strings.Split(window, "-")
+	if len(times) != 2 {
+		return 0, 0, fmt.Errorf("碎片整理的时间窗口不正确：%s", window)
+	}
+	var clocks [2]time.Duration
+	for index, value := range times {
+		clock, err := time.Parse("15:04", strings.TrimSpace(value))
+		if err != nil {
+			return 0, 0, fmt.Errorf("碎片整理的时间窗口不正确：%s", window)
+		}
+		clocks[index] = time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+	}
+	return clocks[0], clocks[1], nil
+}
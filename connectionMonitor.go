@@ -0,0 +1,241 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/connectivity"
+)
+
+// 默认的连接监控间隔
+const defaultMonitorInterval = 5 * time.Second
+
+// ConnectionState 连接状态
+type ConnectionState string
+
+const (
+	ConnectionStateIdle             ConnectionState = "Idle"             // 空闲（没有请求时）
+	ConnectionStateConnecting       ConnectionState = "Connecting"       // 连接中
+	ConnectionStateReady            ConnectionState = "Ready"            // 已连接
+	ConnectionStateTransientFailure ConnectionState = "TransientFailure" // 连接失败（会自动重连）
+	ConnectionStateShutdown         ConnectionState = "Shutdown"         // 已关闭
+)
+
+// 监控连接状态、当前连接的节点、集群Leader
+type connectionMonitor struct {
+	etcdCli        *etcdClient
+	interval       time.Duration
+	startOnce      sync.Once
+	lock           sync.RWMutex
+	state          ConnectionState
+	endpoint       string            // 当前连接的节点
+	leader         uint64            // 集群Leader的节点ID
+	probed         bool              // 是否已成功检测过（首次检测到Leader时不触发OnLeaderChange）
+	members        map[uint64]string // 节点ID => 节点地址
+	onStateChanges []func(old ConnectionState, new ConnectionState)
+	onLeaderChange []func(old uint64, new uint64)
+}
+
+// etcdCli为原始连接（不带KEY前缀），同一个连接的客户端共用一个监控
+func newConnectionMonitor(etcdCli *etcdClient, interval time.Duration) *connectionMonitor {
+	if interval <= 0 {
+		interval = defaultMonitorInterval
+	}
+	return &connectionMonitor{
+		etcdCli:  etcdCli,
+		interval: interval,
+		state:    ConnectionStateShutdown,
+		members:  make(map[uint64]string),
+	}
+}
+
+// 首次使用时才开始监控（重复调用无效）
+func (receiver *connectionMonitor) start() {
+	receiver.startOnce.Do(func() {
+		if receiver.etcdCli == nil || receiver.etcdCli.ActiveConnection() == nil {
+			return
+		}
+		receiver.lock.Lock()
+		receiver.state = toConnectionState(receiver.etcdCli.ActiveConnection().GetState())
+		receiver.lock.Unlock()
+		go receiver.watchState()
+		go receiver.probeLoop()
+	})
+}
+
+// Monitor 客户端的连接监控（同一个连接的客户端共用一个监控）
+func Monitor(client IClient) IConnectionMonitor {
+	if failover, ok := unwrapFailover(client); ok {
+		return &failoverMonitor{client: failover}
+	}
+	monitor := clientOf(client).monitor
+	monitor.start()
+	return monitor
+}
+
+func (receiver *connectionMonitor) ConnectionState() ConnectionState {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.state
+}
+
+func (receiver *connectionMonitor) CurrentEndpoint() string {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.endpoint
+}
+
+func (receiver *connectionMonitor) Leader() uint64 {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.leader
+}
+
+func (receiver *connectionMonitor) OnConnectionStateChange(fn func(old ConnectionState, new ConnectionState)) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.onStateChanges = append(receiver.onStateChanges, fn)
+}
+
+func (receiver *connectionMonitor) OnLeaderChange(fn func(old uint64, new uint64)) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.onLeaderChange = append(receiver.onLeaderChange, fn)
+}
+
+// 监听gRPC的连接状态变化
+func (receiver *connectionMonitor) watchState() {
+	conn := receiver.etcdCli.ActiveConnection()
+	state := conn.GetState()
+	for conn.WaitForStateChange(receiver.etcdCli.Ctx(), state) {
+		state = conn.GetState()
+		receiver.setState(toConnectionState(state))
+	}
+	// 客户端已关闭
+	receiver.setState(ConnectionStateShutdown)
+}
+
+func (receiver *connectionMonitor) setState(state ConnectionState) {
+	receiver.lock.Lock()
+	old := receiver.state
+	receiver.state = state
+	onStateChanges := receiver.onStateChanges
+	receiver.lock.Unlock()
+
+	if old == state {
+		return
+	}
+	if state == ConnectionStateTransientFailure {
+		flog.Warningf("Etcd连接状态：%s => %s", old, state)
+	} else {
+		flog.Infof("Etcd连接状态：%s => %s", old, state)
+	}
+	for _, onStateChange := range onStateChanges {
+		onStateChange(old, state)
+	}
+}
+
+// 定时检测当前连接的节点、集群Leader
+func (receiver *connectionMonitor) probeLoop() {
+	ticker := time.NewTicker(receiver.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := receiver.probe(); err != nil {
+			flog.Debugf("Etcd连接监控检测失败：%s", err.Error())
+		}
+		select {
+		case <-ticker.C:
+		case <-receiver.etcdCli.Ctx().Done():
+			return
+		}
+	}
+}
+
+func (receiver *connectionMonitor) probe() error {
+	ctx, cancel := context.WithTimeout(receiver.etcdCli.Ctx(), receiver.interval)
+	defer cancel()
+
+	// 串行读由当前连接的节点直接处理，响应头中的MemberId即为当前节点
+	rsp, err := receiver.etcdCli.Get(ctx, "\x00", etcdV3.WithSerializable(), etcdV3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+	endpoint, err := receiver.memberEndpoint(ctx, rsp.Header.MemberId)
+	if err != nil {
+		return err
+	}
+
+	statusRsp, err := receiver.etcdCli.Status(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	receiver.lock.Lock()
+	oldEndpoint, oldLeader, probed := receiver.endpoint, receiver.leader, receiver.probed
+	receiver.endpoint, receiver.leader, receiver.probed = endpoint, statusRsp.Leader, true
+	onLeaderChange := receiver.onLeaderChange
+	receiver.lock.Unlock()
+
+	if oldEndpoint != endpoint {
+		flog.Infof("Etcd当前连接的节点：%s", endpoint)
+	}
+	// 首次检测到的Leader不是变化，不通知订阅者
+	if !probed {
+		flog.Infof("Etcd集群Leader：%x", statusRsp.Leader)
+	} else if oldLeader != statusRsp.Leader {
+		flog.Infof("Etcd集群Leader：%x => %x", oldLeader, statusRsp.Leader)
+		for _, fn := range onLeaderChange {
+			fn(oldLeader, statusRsp.Leader)
+		}
+	}
+	return nil
+}
+
+// 根据节点ID获取节点地址（节点列表有变化时重新获取）
+func (receiver *connectionMonitor) memberEndpoint(ctx context.Context, memberId uint64) (string, error) {
+	receiver.lock.RLock()
+	endpoint, exists := receiver.members[memberId]
+	receiver.lock.RUnlock()
+	if exists {
+		return endpoint, nil
+	}
+
+	memberRsp, err := receiver.etcdCli.MemberList(ctx)
+	if err != nil {
+		return "", err
+	}
+	members := make(map[uint64]string, len(memberRsp.Members))
+	for _, member := range memberRsp.Members {
+		if len(member.ClientURLs) > 0 {
+			members[member.ID] = member.ClientURLs[0]
+		}
+	}
+	receiver.lock.Lock()
+	receiver.members = members
+	receiver.lock.Unlock()
+
+	if endpoint, exists = members[memberId]; !exists {
+		return "", fmt.Errorf("节点：%x 不在集群节点列表中", memberId)
+	}
+	return endpoint, nil
+}
+
+func toConnectionState(state connectivity.State) ConnectionState {
+	switch state {
+	case connectivity.Idle:
+		return ConnectionStateIdle
+	case connectivity.Connecting:
+		return ConnectionStateConnecting
+	case connectivity.Ready:
+		return ConnectionStateReady
+	case connectivity.TransientFailure:
+		return ConnectionStateTransientFailure
+	default:
+		return ConnectionStateShutdown
+	}
+}
@@ -0,0 +1,207 @@
+package etcd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/farseer-go/fs/trace"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// 序列号默认每次预留的数量
+const defaultSequenceBlockSize = 100
+
+// 工作节点ID的租约时间（单位s），进程崩溃后，最多在这个时间后释放
+const workerIdTTL = 10
+
+func (receiver *client) Incr(key string, delta int64) (int64, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Incr", key, 0)
+	value, err := incr(receiver.etcdCli, key, delta)
+	receiver.invalidate(key)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return value, err
+}
+
+func (receiver *client) Decr(key string, delta int64) (int64, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Decr", key, 0)
+	value, err := incr(receiver.etcdCli, key, -delta)
+	receiver.invalidate(key)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return value, err
+}
+
+// 通过CAS事务，对KEY的值加上delta（KEY不存在时从0开始），返回加上后的值
+func incr(etcdCli *etcdClient, key string, delta int64) (int64, error) {
+	for {
+		rsp, err := etcdCli.Get(todo, key)
+		if err != nil {
+			return 0, err
+		}
+
+		var value, modRevision int64
+		var opts []etcdV3.OpOption
+		if len(rsp.Kvs) > 0 {
+			if value, err = strconv.ParseInt(string(rsp.Kvs[0].Value), 10, 64); err != nil {
+				return 0, fmt.Errorf("KEY：%s 的值不是整数：%s", key, rsp.Kvs[0].Value)
+			}
+			modRevision = rsp.Kvs[0].ModRevision
+			// 保留原有的租约
+			if rsp.Kvs[0].Lease != 0 {
+				opts = append(opts, etcdV3.WithLease(etcdV3.LeaseID(rsp.Kvs[0].Lease)))
+			}
+		}
+		value += delta
+
+		txnRsp, err := etcdCli.Txn(todo).
+			If(etcdV3.Compare(etcdV3.ModRevision(key), "=", modRevision)).
+			Then(etcdV3.OpPut(key, strconv.FormatInt(value, 10), opts...)).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txnRsp.Succeeded {
+			return value, nil
+		}
+		// 被其它客户端修改，重试
+	}
+}
+
+// Sequence 分布式序列号生成器（单调递增）
+// 每次从etcd预留一段序列号，用完后再预留下一段，避免每个ID都请求一次etcd
+type Sequence struct {
+	etcdCli      *etcdClient
+	traceManager trace.IManager
+	key          string
+	blockSize    int64 // 每次预留的数量
+	lock         sync.Mutex
+	next         int64 // 下一个可用的序列号
+	maxId        int64 // 当前预留段的最大序列号
+}
+
+// NewSequence 分布式序列号生成器，blockSize：每次预留的数量（<=0时默认100）
+func NewSequence(client IClient, name string, blockSize int64) *Sequence {
+	if blockSize <= 0 {
+		blockSize = defaultSequenceBlockSize
+	}
+	cli := clientOf(client)
+	return &Sequence{
+		etcdCli:      cli.etcdCli,
+		traceManager: cli.traceManager,
+		key:          name,
+		blockSize:    blockSize,
+	}
+}
+
+// Next 获取下一个序列号
+func (receiver *Sequence) Next() (int64, error) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	// 当前预留段已用完，预留下一段
+	if receiver.next == 0 || receiver.next > receiver.maxId {
+		traceDetailEtcd := receiver.traceManager.TraceEtcd("SequenceReserve", receiver.key, 0)
+		maxId, err := incr(receiver.etcdCli, receiver.key, receiver.blockSize)
+		traceDetailEtcd.End(err)
+		if err != nil {
+			return 0, err
+		}
+		receiver.next = maxId - receiver.blockSize + 1
+		receiver.maxId = maxId
+	}
+
+	id := receiver.next
+	receiver.next++
+	return id, nil
+}
+
+// WorkerId 工作节点ID（用于雪花算法等需要唯一节点编号的场景）
+// 通过带租约的KEY占用编号，进程退出或崩溃后编号自动释放
+type WorkerId struct {
+	id      int64
+	key     string
+	session *concurrency.Session
+}
+
+// NewWorkerId 分配唯一的工作节点编号（0 ~ maxWorkers-1），name：编号KEY前缀
+func NewWorkerId(client IClient, name string, maxWorkers int64) (*WorkerId, error) {
+	return clientOf(client).workerId(name, maxWorkers)
+}
+
+func (receiver *client) workerId(name string, maxWorkers int64) (*WorkerId, error) {
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("WorkerId", prefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	session, err := concurrency.NewSession(receiver.etcdCli, concurrency.WithTTL(workerIdTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		// 已被占用的编号
+		var rsp *etcdV3.GetResponse
+		if rsp, err = receiver.etcdCli.Get(todo, prefix, etcdV3.WithPrefix(), etcdV3.WithKeysOnly()); err != nil {
+			break
+		}
+		used := make(map[string]struct{}, len(rsp.Kvs))
+		for _, kv := range rsp.Kvs {
+			used[string(kv.Key)] = struct{}{}
+		}
+
+		// 占用第一个空闲的编号
+		var key string
+		var id int64
+		for id = 0; id < maxWorkers; id++ {
+			if _, isUsed := used[prefix+strconv.FormatInt(id, 10)]; !isUsed {
+				key = prefix + strconv.FormatInt(id, 10)
+				break
+			}
+		}
+		if key == "" {
+			err = fmt.Errorf("工作节点ID已用完，最大数量：%d", maxWorkers)
+			break
+		}
+
+		var txnRsp *etcdV3.TxnResponse
+		txnRsp, err = receiver.etcdCli.Txn(todo).
+			If(etcdV3.Compare(etcdV3.CreateRevision(key), "=", 0)).
+			Then(etcdV3.OpPut(key, "", etcdV3.WithLease(session.Lease()))).
+			Commit()
+		if err != nil {
+			break
+		}
+		if txnRsp.Succeeded {
+			return &WorkerId{id: id, key: key, session: session}, nil
+		}
+		// 被其它客户端抢先占用，重试
+	}
+
+	_ = session.Close()
+	return nil, err
+}
+
+// Id 工作节点编号（0 ~ maxWorkers-1）
+func (receiver *WorkerId) Id() int64 {
+	return receiver.id
+}
+
+// Key 占用编号的KEY
+func (receiver *WorkerId) Key() string {
+	return receiver.key
+}
+
+// Done 租约失效（编号可能已被其它进程占用）时关闭
+func (receiver *WorkerId) Done() <-chan struct{} {
+	return receiver.session.Done()
+}
+
+// Close 释放工作节点编号
+func (receiver *WorkerId) Close() error {
+	return receiver.session.Close()
+}
@@ -0,0 +1,210 @@
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/configure"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// 诊断时每个请求的默认超时
+const defaultDiagnoseTimeout = 5 * time.Second
+
+// EndpointStatus 节点的诊断信息
+type EndpointStatus struct {
+	Endpoint    string        // 节点地址
+	Reachable   bool          // 是否可达
+	Error       string        // 不可达的原因
+	Latency     time.Duration // Status请求的耗时
+	MemberId    uint64        // 节点ID
+	Version     string        // 服务端版本
+	DbSize      int64         // 数据库大小（字节）
+	DbSizeInUse int64         // 数据库实际使用的大小（字节）
+	Leader      uint64        // 该节点认为的Leader节点ID
+	IsLeader    bool          // 是否为Leader
+	RaftTerm    uint64        // raft任期
+	RaftIndex   uint64        // raft日志索引
+	Alarms      []string      // 该节点的告警（如：NOSPACE、CORRUPT）
+	Errors      []string      // 该节点上报的错误
+}
+
+// DiagnosticReport 集群的诊断报告
+type DiagnosticReport struct {
+	Endpoints   []EndpointStatus // 每个节点的诊断信息（与配置的顺序一致）
+	AuthEnabled bool             // 是否开启了认证
+	AuthError   string           // 获取认证状态失败的原因
+}
+
+// Reachable 可达的节点数量
+func (receiver *DiagnosticReport) Reachable() int {
+	count := 0
+	for _, endpoint := range receiver.Endpoints {
+		if endpoint.Reachable {
+			count++
+		}
+	}
+	return count
+}
+
+// Healthy 所有节点可达、没有告警和错误、且Leader一致
+func (receiver *DiagnosticReport) Healthy() bool {
+	var leader uint64
+	for _, endpoint := range receiver.Endpoints {
+		if !endpoint.Reachable || len(endpoint.Alarms) > 0 || len(endpoint.Errors) > 0 || endpoint.Leader == 0 {
+			return false
+		}
+		if leader != 0 && leader != endpoint.Leader {
+			return false
+		}
+		leader = endpoint.Leader
+	}
+	return len(receiver.Endpoints) > 0
+}
+
+func (receiver *DiagnosticReport) String() string {
+	var lines []string
+	for _, endpoint := range receiver.Endpoints {
+		if !endpoint.Reachable {
+			lines = append(lines, fmt.Sprintf("%s：不可达，%s", endpoint.Endpoint, endpoint.Error))
+			continue
+		}
+		line := fmt.Sprintf("%s：耗时%s，版本%s，DB大小%d，Leader：%x，任期：%d，索引：%d", endpoint.Endpoint, endpoint.Latency.String(), endpoint.Version, endpoint.DbSize, endpoint.Leader, endpoint.RaftTerm, endpoint.RaftIndex)
+		if len(endpoint.Alarms) > 0 {
+			line += "，告警：" + strings.Join(endpoint.Alarms, "、")
+		}
+		if len(endpoint.Errors) > 0 {
+			line += "，错误：" + strings.Join(endpoint.Errors, "、")
+		}
+		lines = append(lines, line)
+	}
+	if receiver.AuthError != "" {
+		lines = append(lines, "认证状态：获取失败，"+receiver.AuthError)
+	} else {
+		lines = append(lines, fmt.Sprintf("认证状态：%t", receiver.AuthEnabled))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diagnose 根据连接字符串诊断集群，timeout：每个请求（每个节点、告警、认证状态）的超时，为0时使用默认的5秒超时
+func Diagnose(configString string, timeout time.Duration) (*DiagnosticReport, error) {
+	if configString == "" {
+		return nil, fmt.Errorf("连接字符串不能为空")
+	}
+
+	config := configure.ParseString[etcdConfig](configString)
+	if config.Server == "" {
+		return nil, fmt.Errorf("Server配置不正确：%s", configString)
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := open(config)
+	if err != nil {
+		return nil, fmt.Errorf("连接Etcd失败：%s", err.Error())
+	}
+	defer cli.Close()
+
+	if timeout == 0 {
+		timeout = defaultDiagnoseTimeout
+	}
+	return diagnose(context.Background(), cli.(*client).connection(), strings.Split(config.Server, "|"), tlsConfig, timeout), nil
+}
+
+// DiagnoseClient 诊断客户端连接的集群的每个节点（可达性、耗时、版本、DB大小、Leader、raft任期/索引、告警）及认证状态
+// 每个请求使用默认的5秒超时（一个节点不可达不会影响其它节点的诊断），ctx用于取消整个诊断
+func DiagnoseClient(ctx context.Context, client IClient) (*DiagnosticReport, error) {
+	return clientOf(client).diagnose(ctx)
+}
+
+func (receiver *client) diagnose(ctx context.Context) (*DiagnosticReport, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Diagnose", "", 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	conn := receiver.connection()
+	if conn == nil {
+		err = fmt.Errorf("客户端未连接")
+		return nil, err
+	}
+	return diagnose(ctx, conn, conn.Endpoints(), nil, defaultDiagnoseTimeout), nil
+}
+
+// 诊断每个节点，tlsConfig不为nil时，对不可达的节点进行TLS握手以获取明确的失败原因
+// 每个请求单独计算超时，避免前面的请求（如不可达的节点）耗尽后面请求的时间
+func diagnose(ctx context.Context, etcdCli *etcdClient, endpoints []string, tlsConfig *tls.Config, timeout time.Duration) *DiagnosticReport {
+	report := &DiagnosticReport{Endpoints: make([]EndpointStatus, len(endpoints))}
+
+	// 告警是集群级别的，按节点ID归类
+	alarms := make(map[uint64][]string)
+	alarmCtx, alarmCancel := context.WithTimeout(ctx, timeout)
+	alarmRsp, err := etcdCli.AlarmList(alarmCtx)
+	alarmCancel()
+	if err == nil {
+		for _, alarm := range alarmRsp.Alarms {
+			alarms[alarm.MemberID] = append(alarms[alarm.MemberID], alarm.Alarm.String())
+		}
+	}
+
+	var wg sync.WaitGroup
+	for index, endpoint := range endpoints {
+		wg.Add(1)
+		go func(status *EndpointStatus, endpoint string) {
+			defer wg.Done()
+			status.Endpoint = endpoint
+
+			statusCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			startAt := time.Now()
+			rsp, err := etcdCli.Status(statusCtx, endpoint)
+			status.Latency = time.Since(startAt)
+			if err != nil {
+				status.Error = err.Error()
+				// gRPC在TLS握手失败时只会返回超时
+				if tlsConfig != nil {
+					if tlsErr := tlsHandshake(endpoint, tlsConfig, timeout); tlsErr != nil {
+						status.Error = "TLS握手失败：" + tlsErr.Error()
+					}
+				}
+				return
+			}
+
+			*status = newEndpointStatus(endpoint, rsp, status.Latency)
+			status.Alarms = alarms[rsp.Header.MemberId]
+		}(&report.Endpoints[index], endpoint)
+	}
+	wg.Wait()
+
+	authCtx, authCancel := context.WithTimeout(ctx, timeout)
+	defer authCancel()
+	if authRsp, err := etcdCli.AuthStatus(authCtx); err != nil {
+		report.AuthError = err.Error()
+	} else {
+		report.AuthEnabled = authRsp.Enabled
+	}
+	return report
+}
+
+func newEndpointStatus(endpoint string, rsp *etcdV3.StatusResponse, latency time.Duration) EndpointStatus {
+	return EndpointStatus{
+		Endpoint:    endpoint,
+		Reachable:   true,
+		Latency:     latency,
+		MemberId:    rsp.Header.MemberId,
+		Version:     rsp.Version,
+		DbSize:      rsp.DbSize,
+		DbSizeInUse: rsp.DbSizeInUse,
+		Leader:      rsp.Leader,
+		IsLeader:    rsp.Leader == rsp.Header.MemberId,
+		RaftTerm:    rsp.RaftTerm,
+		RaftIndex:   rsp.RaftIndex,
+		Errors:      rsp.Errors,
+	}
+}
@@ -0,0 +1,159 @@
+package etcd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// DiffResult 两个KEY前缀之间的差异（KEY已去掉前缀，按KEY排序）
+type DiffResult struct {
+	Added   []DiffItem // 源有，目标没有
+	Removed []DiffItem // 源没有，目标有
+	Changed []DiffItem // 两边都有，值不同
+	Applied []string   // Sync时已写入目标的KEY（去掉前缀），失败时为失败之前已写入的批次
+}
+
+// DiffItem 差异的KEY
+type DiffItem struct {
+	Key            string // 去掉前缀后的KEY
+	SrcValue       string // 源的值
+	DstValue       string // 目标的值
+	DstModRevision int64  // 目标KEY的修改版本（0：目标没有），Sync时用于检测目标是否被并发修改
+}
+
+// IsEmpty 没有差异
+func (receiver *DiffResult) IsEmpty() bool {
+	return len(receiver.Added) == 0 && len(receiver.Removed) == 0 && len(receiver.Changed) == 0
+}
+
+func (receiver *DiffResult) String() string {
+	var lines []string
+	for _, item := range receiver.Added {
+		lines = append(lines, "+ "+item.Key)
+	}
+	for _, item := range receiver.Changed {
+		lines = append(lines, "~ "+item.Key)
+	}
+	for _, item := range receiver.Removed {
+		lines = append(lines, "- "+item.Key)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SyncOptions 同步的选项
+type SyncOptions struct {
+	Delete    bool // 删除目标中多出来的KEY（默认保留）
+	BatchSize int  // 每个事务包含的KEY数量，默认100，最大128
+	DryRun    bool // 只返回差异，不实际同步
+}
+
+// Diff 比较srcClient的srcPrefix与dstClient的dstPrefix之间的差异（可以是同一个客户端的两个前缀）
+func Diff(srcClient IClient, srcPrefix string, dstClient IClient, dstPrefix string) (*DiffResult, error) {
+	srcKvs, err := srcClient.GetPrefixKey(srcPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("读取源失败：%s", err.Error())
+	}
+	dstKvs, err := dstClient.GetPrefixKey(dstPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("读取目标失败：%s", err.Error())
+	}
+
+	dst := make(map[string]*KeyValue, len(dstKvs))
+	for key, kv := range dstKvs {
+		dst[strings.TrimPrefix(key, dstPrefix)] = kv
+	}
+
+	result := &DiffResult{}
+	for key, srcKv := range srcKvs {
+		key = strings.TrimPrefix(key, srcPrefix)
+		dstKv, exists := dst[key]
+		switch {
+		case !exists:
+			result.Added = append(result.Added, DiffItem{Key: key, SrcValue: srcKv.Value})
+		case dstKv.Value != srcKv.Value:
+			result.Changed = append(result.Changed, DiffItem{Key: key, SrcValue: srcKv.Value, DstValue: dstKv.Value, DstModRevision: dstKv.ModRevision})
+		}
+		delete(dst, key)
+	}
+	for key, dstKv := range dst {
+		result.Removed = append(result.Removed, DiffItem{Key: key, DstValue: dstKv.Value, DstModRevision: dstKv.ModRevision})
+	}
+
+	for _, items := range [][]DiffItem{result.Added, result.Removed, result.Changed} {
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	}
+	return result, nil
+}
+
+// Sync 将srcPrefix同步到dstPrefix，返回同步的差异
+// 按批次以事务写入，目标KEY在比较之后被其它客户端修改时，该批次不会写入并返回错误，已写入的KEY见result.Applied
+func Sync(srcClient IClient, srcPrefix string, dstClient IClient, dstPrefix string, options SyncOptions) (*DiffResult, error) {
+	result, err := Diff(srcClient, srcPrefix, dstClient, dstPrefix)
+	if err != nil {
+		return result, err
+	}
+	return result, clientOf(dstClient).sync(result, dstPrefix, options)
+}
+
+func (receiver *client) sync(result *DiffResult, dstPrefix string, options SyncOptions) error {
+	if !options.Delete {
+		result.Removed = nil
+	}
+	if options.DryRun {
+		return nil
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	} else if options.BatchSize > maxImportBatchSize {
+		options.BatchSize = maxImportBatchSize
+	}
+
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Sync", dstPrefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	var keys []string
+	var cmps []etcdV3.Cmp
+	var ops []etcdV3.Op
+	for _, item := range result.Added {
+		keys = append(keys, item.Key)
+		cmps = append(cmps, etcdV3.Compare(etcdV3.CreateRevision(dstPrefix+item.Key), "=", 0))
+		ops = append(ops, etcdV3.OpPut(dstPrefix+item.Key, item.SrcValue))
+	}
+	for _, item := range result.Changed {
+		keys = append(keys, item.Key)
+		cmps = append(cmps, etcdV3.Compare(etcdV3.ModRevision(dstPrefix+item.Key), "=", item.DstModRevision))
+		ops = append(ops, etcdV3.OpPut(dstPrefix+item.Key, item.SrcValue))
+	}
+	for _, item := range result.Removed {
+		keys = append(keys, item.Key)
+		cmps = append(cmps, etcdV3.Compare(etcdV3.ModRevision(dstPrefix+item.Key), "=", item.DstModRevision))
+		ops = append(ops, etcdV3.OpDelete(dstPrefix+item.Key))
+	}
+
+	for start := 0; start < len(ops); start += options.BatchSize {
+		end := start + options.BatchSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		var rsp *etcdV3.TxnResponse
+		rsp, err = receiver.etcdCli.Txn(todo).If(cmps[start:end]...).Then(ops[start:end]...).Commit()
+		// 请求失败时不确定是否已写入，同样删除本地缓存
+		for _, key := range keys[start:end] {
+			receiver.invalidate(dstPrefix + key)
+		}
+		if err != nil {
+			err = fmt.Errorf("同步失败，已同步%d个KEY：%s", len(result.Applied), err.Error())
+			return err
+		}
+		if !rsp.Succeeded {
+			err = fmt.Errorf("目标在同步期间被其它客户端修改，已同步%d个KEY", len(result.Applied))
+			return err
+		}
+		result.Applied = append(result.Applied, keys[start:end]...)
+	}
+	return nil
+}
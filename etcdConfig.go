@@ -1,14 +1,37 @@
 package etcd
 
 type etcdConfig struct {
-	Server               string // 服务端地址
-	DialTimeout          int    // 连接超时时间（ms)
-	DialKeepAliveTime    int    // 对服务器进行ping的时间（ms)
-	DialKeepAliveTimeout int    // 客户端等待响应的超时时间（ms)
-	MaxCallSendMsgSize   int    // 客户端的请求发送限制，单位是字节。0，则默认为2.0 MiB（2 * 1024 * 1024）。
-	MaxCallRecvMsgSize   int    // 客户端的响应接收限制，单位是字节。0，则默认不限制
-	Username             string // 用户名
-	Password             string // 密码
-	RejectOldCluster     bool   // 拒绝过时的集群创建客户端。
-	PermitWithoutStream  bool   // 允许客户端在没有任何活动流（RPC）的情况下向服务器发送keepalive pings。
+	Server                  string // 服务端地址
+	DialTimeout             int    // 连接超时时间（ms)
+	DialKeepAliveTime       int    // 对服务器进行ping的时间（ms)
+	DialKeepAliveTimeout    int    // 客户端等待响应的超时时间（ms)
+	MaxCallSendMsgSize      int    // 客户端的请求发送限制，单位是字节。0，则默认为2.0 MiB（2 * 1024 * 1024）。
+	MaxCallRecvMsgSize      int    // 客户端的响应接收限制，单位是字节。0，则默认不限制
+	Username                string // 用户名
+	Password                string // 密码
+	RejectOldCluster        bool   // 拒绝过时的集群创建客户端。
+	PermitWithoutStream     bool   // 允许客户端在没有任何活动流（RPC）的情况下向服务器发送keepalive pings。
+	Tls                     bool   // 使用TLS连接（配置了以下任意一项证书时自动开启，未配置CA时使用系统根证书）
+	CaFile                  string // CA证书文件
+	Ca                      string // CA证书内容（PEM或base64编码的PEM）
+	CertFile                string // 客户端证书文件（双向认证）
+	Cert                    string // 客户端证书内容（PEM或base64编码的PEM）
+	KeyFile                 string // 客户端私钥文件（双向认证）
+	Key                     string // 客户端私钥内容（PEM或base64编码的PEM）
+	ServerName              string // 校验服务端证书时使用的域名
+	InsecureSkipVerify      bool   // 不校验服务端证书（仅用于测试环境）
+	Namespace               string // KEY前缀（命名空间），所有的KEY都会自动加上这个前缀
+	CacheSize               int    // Get的本地缓存数量（LRU），0：不缓存
+	CacheTTL                int    // Get的本地缓存时间（ms），0：不过期（KEY有变化时通过Watch自动失效）
+	MonitorInterval         int    // 连接监控检测当前节点、Leader的间隔（ms），默认5000
+	AutoCompactionMode      string // 自动压缩模式：periodic（按时间保留）、revision（按版本数保留），为空时不开启
+	AutoCompactionRetention string // periodic：保留的时间，如：1h；revision：保留的版本数，如：10000
+	DefragWindow            string // 自动压缩后在这个时间窗口内进行碎片整理，如：02:00-04:00
+	BackupDir               string // 定时备份的目录，为空时不开启
+	BackupInterval          string // 定时备份的间隔，如：6h
+	BackupRetain            int    // 定时备份保留的份数，默认7
+	StandbyServer           string // 备用集群的服务端地址（除地址外，其它配置与主集群相同），为空时不开启故障转移
+	FailoverInterval        int    // 主集群健康检查的间隔（ms），默认2000
+	FailoverThreshold       int    // 主集群连续多少次健康检查失败后切换到备用集群，默认3
+	FailBackThreshold       int    // 主集群连续多少次健康检查成功后切回主集群，0：不自动切回
 }
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/etcd/etcdTest"
+	"github.com/farseer-go/fs/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdTest(t *testing.T) {
+	cluster := etcdTest.Start(t, "embed")
+	client := container.Resolve[etcd.IClient]("embed")
+	defer client.Close()
+
+	_, err := client.Put("/embed/a1", "1")
+	assert.NoError(t, err)
+	result, err := client.Get("/embed/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Len(t, cluster.Endpoints(), 1)
+	assert.NotNil(t, cluster.WaitLeader())
+
+	// 关闭后从容器中移除
+	cluster.Close()
+	assert.False(t, container.IsRegister[etcd.IClient]("embed"))
+}
+
+func TestEtcdTestCluster(t *testing.T) {
+	cluster := etcdTest.StartCluster(t, "embedCluster", 3)
+	client := cluster.Client()
+	defer client.Close()
+
+	_, err := client.Put("/embed/a1", "1")
+	assert.NoError(t, err)
+
+	// Leader丢失后，剩余的节点重新选出Leader，客户端仍然可用
+	oldLeader := cluster.StopLeader()
+	assert.False(t, oldLeader.IsRunning())
+	newLeader := cluster.WaitLeader()
+	assert.NotEqual(t, oldLeader.Name, newLeader.Name)
+	assert.Eventually(t, func() bool {
+		_, err = client.Put("/embed/a1", "2")
+		return err == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// 重启后重新加入集群，数据同步
+	oldLeader.Restart()
+	assert.True(t, oldLeader.IsRunning())
+	result, err := client.Get("/embed/a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result.Value)
+}
@@ -0,0 +1,10 @@
+package test
+
+import (
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs"
+)
+
+func init() {
+	fs.Initialize[etcd.Module]("test etcdTest")
+}
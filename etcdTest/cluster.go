@@ -0,0 +1,233 @@
+// Package etcdTest 集成测试使用的嵌入式etcd：在临时目录、随机端口上启动真实的etcd，并注册etcd.IClient到容器
+package etcdTest
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farseer-go/etcd"
+	"github.com/farseer-go/fs/container"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// 等待节点启动、选出Leader的超时时间
+const startTimeout = 30 * time.Second
+
+// Cluster 嵌入式etcd集群
+type Cluster struct {
+	Name    string    // 注册到容器的名称
+	Members []*Member // 集群的节点
+	t       testing.TB
+	lock    sync.Mutex
+	closed  bool
+}
+
+// Member 集群的节点
+type Member struct {
+	Name      string // 节点名称
+	ClientURL string // 客户端地址
+	PeerURL   string // 节点之间通讯的地址
+	Dir       string // 数据目录
+	cluster   *Cluster
+	etcd      *embed.Etcd // 已停止时为nil
+}
+
+// Start 启动单节点的嵌入式etcd，并以name注册etcd.IClient到容器，测试结束时自动关闭
+func Start(t testing.TB, name string) *Cluster {
+	t.Helper()
+	return StartCluster(t, name, 1)
+}
+
+// StartCluster 启动size个节点的嵌入式etcd集群（用于测试Leader丢失），并以name注册etcd.IClient到容器，测试结束时自动关闭
+func StartCluster(t testing.TB, name string, size int) *Cluster {
+	t.Helper()
+	if size <= 0 {
+		size = 1
+	}
+
+	cluster := &Cluster{Name: name, t: t}
+	var initialCluster []string
+	for index := 0; index < size; index++ {
+		member := &Member{
+			Name:      fmt.Sprintf("%s%d", name, index),
+			ClientURL: "http://" + freeAddress(t),
+			PeerURL:   "http://" + freeAddress(t),
+			Dir:       t.TempDir(),
+			cluster:   cluster,
+		}
+		cluster.Members = append(cluster.Members, member)
+		initialCluster = append(initialCluster, member.Name+"="+member.PeerURL)
+	}
+	t.Cleanup(cluster.Close)
+
+	// 多节点时需要多数节点启动后才能选出Leader，所以先全部启动，再等待就绪
+	for _, member := range cluster.Members {
+		if err := member.start(strings.Join(initialCluster, ","), embed.ClusterStateFlagNew); err != nil {
+			t.Fatalf("启动嵌入式etcd失败：%s", err.Error())
+		}
+	}
+	for _, member := range cluster.Members {
+		if err := member.waitReady(); err != nil {
+			t.Fatalf("启动嵌入式etcd失败：%s", err.Error())
+		}
+	}
+
+	if err := etcd.Register(name, cluster.ConfigString()); err != nil {
+		t.Fatalf("注册etcd客户端失败：%s", err.Error())
+	}
+	return cluster
+}
+
+// 获取一个空闲的本机端口
+func freeAddress(t testing.TB) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("获取空闲端口失败：%s", err.Error())
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().String()
+}
+
+// Client 从容器中取出客户端（使用完后需Close）
+func (receiver *Cluster) Client() etcd.IClient {
+	return container.Resolve[etcd.IClient](receiver.Name)
+}
+
+// Endpoints 所有节点的客户端地址
+func (receiver *Cluster) Endpoints() []string {
+	var endpoints []string
+	for _, member := range receiver.Members {
+		endpoints = append(endpoints, strings.TrimPrefix(member.ClientURL, "http://"))
+	}
+	return endpoints
+}
+
+// ConfigString 客户端的配置（与配置文件中的Etcd节点格式相同）
+func (receiver *Cluster) ConfigString() string {
+	return "Server=" + strings.Join(receiver.Endpoints(), "|") + ",DialTimeout=5000"
+}
+
+// Leader 当前的Leader节点（没有Leader时返回nil）
+func (receiver *Cluster) Leader() *Member {
+	for _, member := range receiver.Members {
+		if member.IsLeader() {
+			return member
+		}
+	}
+	return nil
+}
+
+// WaitLeader 等待选出Leader（超时时测试失败）
+func (receiver *Cluster) WaitLeader() *Member {
+	receiver.t.Helper()
+	deadline := time.Now().Add(startTimeout)
+	for time.Now().Before(deadline) {
+		if leader := receiver.Leader(); leader != nil {
+			return leader
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	receiver.t.Fatalf("等待etcd选出Leader超时")
+	return nil
+}
+
+// StopLeader 停止当前的Leader节点（模拟Leader丢失），返回被停止的节点
+func (receiver *Cluster) StopLeader() *Member {
+	receiver.t.Helper()
+	leader := receiver.WaitLeader()
+	leader.Stop()
+	return leader
+}
+
+// Close 关闭所有的节点，并从容器中移除客户端（测试结束时自动调用）
+func (receiver *Cluster) Close() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.closed {
+		return
+	}
+	receiver.closed = true
+
+	etcd.Unregister(receiver.Name)
+	for _, member := range receiver.Members {
+		member.Stop()
+	}
+}
+
+func (receiver *Member) start(initialCluster string, clusterState string) error {
+	clientURL, _ := url.Parse(receiver.ClientURL)
+	peerURL, _ := url.Parse(receiver.PeerURL)
+
+	config := embed.NewConfig()
+	config.Name = receiver.Name
+	config.Dir = receiver.Dir
+	config.ListenClientUrls, config.AdvertiseClientUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	config.ListenPeerUrls, config.AdvertisePeerUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	config.InitialCluster = initialCluster
+	config.InitialClusterToken = receiver.cluster.Name
+	config.ClusterState = clusterState
+	// 停止节点时etcd会输出error级别的日志（连接已关闭），不需要输出到测试结果中
+	config.LogLevel = "fatal"
+
+	server, err := embed.StartEtcd(config)
+	if err != nil {
+		return err
+	}
+	receiver.etcd = server
+	return nil
+}
+
+func (receiver *Member) waitReady() error {
+	select {
+	case <-receiver.etcd.Server.ReadyNotify():
+		return nil
+	case err := <-receiver.etcd.Err():
+		return err
+	case <-time.After(startTimeout):
+		receiver.etcd.Server.Stop()
+		return fmt.Errorf("节点%s启动超时", receiver.Name)
+	}
+}
+
+// IsRunning 节点是否在运行
+func (receiver *Member) IsRunning() bool {
+	return receiver.etcd != nil
+}
+
+// IsLeader 节点是否为Leader
+func (receiver *Member) IsLeader() bool {
+	return receiver.etcd != nil && receiver.etcd.Server.Leader() == receiver.etcd.Server.MemberID()
+}
+
+// Stop 停止节点（数据目录保留，可通过Restart重新启动）
+func (receiver *Member) Stop() {
+	if receiver.etcd != nil {
+		receiver.etcd.Close()
+		receiver.etcd = nil
+	}
+}
+
+// Restart 使用原来的数据目录、端口重新启动节点
+func (receiver *Member) Restart() {
+	receiver.cluster.t.Helper()
+	if receiver.etcd != nil {
+		return
+	}
+
+	var initialCluster []string
+	for _, member := range receiver.cluster.Members {
+		initialCluster = append(initialCluster, member.Name+"="+member.PeerURL)
+	}
+	err := receiver.start(strings.Join(initialCluster, ","), embed.ClusterStateFlagExisting)
+	if err == nil {
+		err = receiver.waitReady()
+	}
+	if err != nil {
+		receiver.cluster.t.Fatalf("重启嵌入式etcd失败：%s", err.Error())
+	}
+}
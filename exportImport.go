@@ -0,0 +1,321 @@
+package etcd
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/farseer-go/fs/snc"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormat 导出的格式
+type ExportFormat string
+
+const (
+	FormatJson   ExportFormat = "json"   // 整个文档为一个JSON
+	FormatYaml   ExportFormat = "yaml"   // 整个文档为一个YAML
+	FormatNdjson ExportFormat = "ndjson" // 每行一个KV的JSON（适合大量KEY）
+)
+
+// ImportConflict 导入时KEY已存在的处理方式
+type ImportConflict string
+
+const (
+	ConflictOverwrite ImportConflict = "overwrite" // 覆盖
+	ConflictSkip      ImportConflict = "skip"      // 跳过
+	ConflictFail      ImportConflict = "fail"      // 不导入任何KEY，返回错误
+)
+
+// 每个事务默认包含的KEY数量
+const defaultImportBatchSize = 100
+
+// 每个事务最多包含的KEY数量（etcd默认限制每个事务最多128个操作）
+const maxImportBatchSize = 128
+
+// ExportData 导出的文档（json、yaml）
+type ExportData struct {
+	Prefix   string       `json:"prefix" yaml:"prefix"`     // 导出时的KEY前缀
+	Revision int64        `json:"revision" yaml:"revision"` // 导出时的集群Revision
+	Items    []ExportItem `json:"items" yaml:"items"`
+}
+
+// ExportItem 导出的KV
+type ExportItem struct {
+	Key      string `json:"key" yaml:"key"`                               // 去掉前缀后的KEY
+	Value    string `json:"value" yaml:"value"`                           // 值
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"` // 值不是UTF-8文本时为base64
+	LeaseTTL int64  `json:"leaseTTL,omitempty" yaml:"leaseTTL,omitempty"` // 导出时租约的剩余时间（单位s），0：没有租约
+}
+
+// ImportOptions 导入的选项
+type ImportOptions struct {
+	Conflict  ImportConflict // KEY已存在时的处理方式，默认：overwrite
+	BatchSize int            // 每个事务包含的KEY数量，默认100，最大128
+	DryRun    bool           // 只统计，不实际导入
+}
+
+// ImportResult 导入的结果
+type ImportResult struct {
+	Total   int // 文档中的KEY数量
+	Created int // 新增的KEY数量
+	Updated int // 覆盖的KEY数量
+	Skipped int // 已存在而跳过的KEY数量
+}
+
+// Export 将KEY前缀下的所有KV（含租约剩余时间）导出为json、yaml、ndjson，返回导出的KEY数量（导出的KEY会去掉前缀）
+func Export(ctx context.Context, client IClient, prefix string, format ExportFormat, writer io.Writer) (int, error) {
+	return clientOf(client).export(ctx, prefix, format, writer)
+}
+
+func (receiver *client) export(ctx context.Context, prefix string, format ExportFormat, writer io.Writer) (int, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Export", prefix, 0)
+	data, err := receiver.exportData(ctx, prefix)
+	if err == nil {
+		err = writeExportData(data, format, writer)
+	}
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return 0, err
+	}
+	return len(data.Items), nil
+}
+
+// 读取前缀下的所有KV及租约的剩余时间
+func (receiver *client) exportData(ctx context.Context, prefix string) (*ExportData, error) {
+	rsp, err := receiver.etcdCli.Get(ctx, prefix, etcdV3.WithPrefix(), etcdV3.WithSort(etcdV3.SortByKey, etcdV3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	data := &ExportData{Prefix: prefix, Revision: rsp.Header.Revision, Items: make([]ExportItem, 0, len(rsp.Kvs))}
+	leaseTTLs := make(map[int64]int64)
+	for _, kv := range rsp.Kvs {
+		item := ExportItem{Key: strings.TrimPrefix(string(kv.Key), prefix), Value: string(kv.Value)}
+		if !utf8.Valid(kv.Value) {
+			item.Value, item.Encoding = base64.StdEncoding.EncodeToString(kv.Value), "base64"
+		}
+		if kv.Lease != 0 {
+			ttl, exists := leaseTTLs[kv.Lease]
+			if !exists {
+				ttlRsp, err := receiver.etcdCli.TimeToLive(ctx, etcdV3.LeaseID(kv.Lease))
+				if err != nil {
+					return nil, err
+				}
+				ttl = ttlRsp.TTL
+				leaseTTLs[kv.Lease] = ttl
+			}
+			// 租约已过期的KEY不导出
+			if ttl <= 0 {
+				continue
+			}
+			item.LeaseTTL = ttl
+		}
+		data.Items = append(data.Items, item)
+	}
+	return data, nil
+}
+
+func writeExportData(data *ExportData, format ExportFormat, writer io.Writer) error {
+	switch format {
+	case FormatJson:
+		jsonValue, err := snc.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(jsonValue)
+		return err
+	case FormatYaml:
+		encoder := yaml.NewEncoder(writer)
+		if err := encoder.Encode(data); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatNdjson:
+		bufWriter := bufio.NewWriter(writer)
+		for _, item := range data.Items {
+			jsonValue, err := snc.Marshal(item)
+			if err != nil {
+				return err
+			}
+			_, _ = bufWriter.Write(jsonValue)
+			_ = bufWriter.WriteByte('\n')
+		}
+		return bufWriter.Flush()
+	default:
+		return fmt.Errorf("导出格式不正确：%s", format)
+	}
+}
+
+func readExportData(format ExportFormat, reader io.Reader) ([]ExportItem, error) {
+	var data ExportData
+	switch format {
+	case FormatJson:
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		if err = snc.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("JSON解析失败：%s", err.Error())
+		}
+	case FormatYaml:
+		if err := yaml.NewDecoder(reader).Decode(&data); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("YAML解析失败：%s", err.Error())
+		}
+	case FormatNdjson:
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(strings.TrimSpace(scanner.Text())) == 0 {
+				continue
+			}
+			var item ExportItem
+			if err := snc.Unmarshal(scanner.Bytes(), &item); err != nil {
+				return nil, fmt.Errorf("第%d行JSON解析失败：%s", line, err.Error())
+			}
+			data.Items = append(data.Items, item)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("导入格式不正确：%s", format)
+	}
+	return data.Items, nil
+}
+
+// Import 将导出的KV导入到KEY前缀下（按批次以事务写入）
+func Import(ctx context.Context, client IClient, prefix string, format ExportFormat, reader io.Reader, options ImportOptions) (*ImportResult, error) {
+	return clientOf(client).importData(ctx, prefix, format, reader, options)
+}
+
+func (receiver *client) importData(ctx context.Context, prefix string, format ExportFormat, reader io.Reader, options ImportOptions) (*ImportResult, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Import", prefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	items, err := readExportData(format, reader)
+	if err != nil {
+		return nil, err
+	}
+	result, err := receiver.importItems(ctx, prefix, items, options)
+	return result, err
+}
+
+func (receiver *client) importItems(ctx context.Context, prefix string, items []ExportItem, options ImportOptions) (*ImportResult, error) {
+	if options.Conflict == "" {
+		options.Conflict = ConflictOverwrite
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	} else if options.BatchSize > maxImportBatchSize {
+		options.BatchSize = maxImportBatchSize
+	}
+
+	// 先解码所有的值，避免导入一半时才发现数据错误
+	values := make([]string, len(items))
+	for index, item := range items {
+		values[index] = item.Value
+		if item.Encoding == "base64" {
+			value, err := base64.StdEncoding.DecodeString(item.Value)
+			if err != nil {
+				return nil, fmt.Errorf("KEY：%s 的值base64解码失败：%s", item.Key, err.Error())
+			}
+			values[index] = string(value)
+		}
+	}
+
+	// 已存在的KEY
+	rsp, err := receiver.etcdCli.Get(ctx, prefix, etcdV3.WithPrefix(), etcdV3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+	existsKeys := make(map[string]bool, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		existsKeys[string(kv.Key)] = true
+	}
+
+	result := &ImportResult{Total: len(items)}
+	if options.Conflict == ConflictFail {
+		for _, item := range items {
+			if existsKeys[prefix+item.Key] {
+				return result, fmt.Errorf("KEY：%s 已存在", prefix+item.Key)
+			}
+		}
+	}
+
+	// 相同TTL的KEY共用一个租约
+	leases := make(map[int64]etcdV3.LeaseID)
+	var ops []etcdV3.Op
+	var cmps []etcdV3.Cmp
+	var created, updated int
+	for index, item := range items {
+		key := prefix + item.Key
+		switch {
+		case !existsKeys[key]:
+			// 导入期间被其它客户端创建时，整个事务失败
+			cmps = append(cmps, etcdV3.Compare(etcdV3.CreateRevision(key), "=", 0))
+			created++
+		case options.Conflict == ConflictSkip:
+			result.Skipped++
+			continue
+		default:
+			updated++
+		}
+
+		var opts []etcdV3.OpOption
+		if item.LeaseTTL > 0 && !options.DryRun {
+			leaseId, exists := leases[item.LeaseTTL]
+			if !exists {
+				leaseRsp, err := receiver.etcdCli.Grant(ctx, item.LeaseTTL)
+				if err != nil {
+					return result, err
+				}
+				leaseId = leaseRsp.ID
+				leases[item.LeaseTTL] = leaseId
+			}
+			opts = append(opts, etcdV3.WithLease(leaseId))
+		}
+		ops = append(ops, etcdV3.OpPut(key, values[index], opts...))
+
+		if len(ops) >= options.BatchSize {
+			if err = receiver.commitImport(ctx, cmps, ops, options.DryRun); err != nil {
+				return result, err
+			}
+			result.Created, result.Updated = result.Created+created, result.Updated+updated
+			ops, cmps, created, updated = nil, nil, 0, 0
+		}
+	}
+	// 最后一批
+	if len(ops) > 0 {
+		if err = receiver.commitImport(ctx, cmps, ops, options.DryRun); err != nil {
+			return result, err
+		}
+		result.Created, result.Updated = result.Created+created, result.Updated+updated
+	}
+	if !options.DryRun {
+		receiver.invalidatePrefix(prefix)
+	}
+	return result, nil
+}
+
+// 以事务提交一批KEY
+func (receiver *client) commitImport(ctx context.Context, cmps []etcdV3.Cmp, ops []etcdV3.Op, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	rsp, err := receiver.etcdCli.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !rsp.Succeeded {
+		return fmt.Errorf("导入期间有KEY被其它客户端创建，本批次未导入")
+	}
+	return nil
+}
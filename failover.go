@@ -0,0 +1,423 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	ClusterPrimary = "primary" // 主集群
+	ClusterStandby = "standby" // 备用集群
+)
+
+// 默认的健康检查间隔
+const defaultFailoverInterval = 2 * time.Second
+
+// 默认连续失败多少次后切换到备用集群
+const defaultFailoverThreshold = 3
+
+// 主备集群的切换状态（与命名空间客户端共用）
+type failoverState struct {
+	primary        *client // 主集群（用于健康检查）
+	standby        *client // 备用集群（用于健康检查）
+	interval       time.Duration
+	threshold      int // 连续失败多少次后切换到备用集群
+	backThreshold  int // 主集群连续健康多少次后切回，0：不自动切回
+	lock           sync.RWMutex
+	isStandby      bool
+	onFailovers    []func(from string, to string)
+	watches        map[*failoverWatch]struct{}
+	primaryFails   int // 主集群连续失败的次数
+	primaryHealthy int // 主集群连续健康的次数
+	cancel         context.CancelFunc
+}
+
+// 故障转移时，需要在新集群上重新注册的Watch
+type failoverWatch struct {
+	ctx       context.Context
+	state     *failoverState
+	primary   IClient
+	standby   IClient
+	key       string
+	isPrefix  bool
+	fn        func(event WatchEvent)
+	lock      sync.Mutex
+	cancel    context.CancelFunc // 取消当前集群上的Watch
+	isStandby bool               // 当前监听的集群
+	revisions [2]int64           // 主、备集群上已处理到的revision（切换到该集群时从之后继续监听）
+}
+
+// 主备集群的故障转移客户端：读写都使用主集群，主集群健康检查连续失败后切换到备用集群
+type failoverClient struct {
+	primary IClient
+	standby IClient
+	state   *failoverState
+	isView  bool // 通过WithNamespace创建（Close时不关闭连接）
+}
+
+// 创建主备集群的故障转移客户端（备用集群除了Server外，其它配置与主集群相同）
+func openFailover(config etcdConfig) (IClient, error) {
+	primary, err := open(config)
+	if err != nil {
+		return primary, err
+	}
+	standbyConfig := config
+	standbyConfig.Server = config.StandbyServer
+	standby, err := open(standbyConfig)
+	if err != nil {
+		primary.Close()
+		return standby, err
+	}
+
+	state := &failoverState{
+		primary:       primary.(*client),
+		standby:       standby.(*client),
+		interval:      time.Duration(config.FailoverInterval) * time.Millisecond,
+		threshold:     config.FailoverThreshold,
+		backThreshold: config.FailBackThreshold,
+		watches:       make(map[*failoverWatch]struct{}),
+	}
+	if state.interval <= 0 {
+		state.interval = defaultFailoverInterval
+	}
+	if state.threshold <= 0 {
+		state.threshold = defaultFailoverThreshold
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	go state.healthCheck(ctx)
+	return &failoverClient{primary: primary, standby: standby, state: state}, nil
+}
+
+// ActiveCluster 当前使用的集群：primary、standby（未配置备用集群时始终为primary）
+func ActiveCluster(client IClient) string {
+	if failover, ok := unwrapFailover(client); ok && failover.state.isActiveStandby() {
+		return ClusterStandby
+	}
+	return ClusterPrimary
+}
+
+// OnFailover 订阅主备集群的切换（未配置备用集群时不会触发）
+func OnFailover(client IClient, fn func(from string, to string)) {
+	failover, ok := unwrapFailover(client)
+	if !ok {
+		return
+	}
+	failover.state.lock.Lock()
+	defer failover.state.lock.Unlock()
+	failover.state.onFailovers = append(failover.state.onFailovers, fn)
+}
+
+// 取出故障转移客户端（被故障注入、录制包装时，取出被包装的客户端）
+func unwrapFailover(c IClient) (*failoverClient, bool) {
+	for {
+		switch cli := c.(type) {
+		case *failoverClient:
+			return cli, true
+		case wrappedClient:
+			c = cli.unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// 故障转移客户端的连接监控：状态、节点、Leader取当前使用的集群，订阅时主、备集群的变化都会通知
+type failoverMonitor struct {
+	client *failoverClient
+}
+
+func (receiver *failoverMonitor) ConnectionState() ConnectionState {
+	return Monitor(receiver.client.active()).ConnectionState()
+}
+
+func (receiver *failoverMonitor) CurrentEndpoint() string {
+	return Monitor(receiver.client.active()).CurrentEndpoint()
+}
+
+func (receiver *failoverMonitor) Leader() uint64 {
+	return Monitor(receiver.client.active()).Leader()
+}
+
+func (receiver *failoverMonitor) OnConnectionStateChange(fn func(old ConnectionState, new ConnectionState)) {
+	Monitor(receiver.client.primary).OnConnectionStateChange(fn)
+	Monitor(receiver.client.standby).OnConnectionStateChange(fn)
+}
+
+func (receiver *failoverMonitor) OnLeaderChange(fn func(old uint64, new uint64)) {
+	Monitor(receiver.client.primary).OnLeaderChange(fn)
+	Monitor(receiver.client.standby).OnLeaderChange(fn)
+}
+
+// 当前使用的集群
+func (receiver *failoverClient) active() IClient {
+	if receiver.state.isActiveStandby() {
+		return receiver.standby
+	}
+	return receiver.primary
+}
+
+func (receiver *failoverState) isActiveStandby() bool {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.isStandby
+}
+
+// 定时检查主集群（已切换到备用集群时，同时检查备用集群是否健康）
+func (receiver *failoverState) healthCheck(ctx context.Context) {
+	ticker := time.NewTicker(receiver.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		primaryRevision, primaryErr := receiver.check(ctx, receiver.primary)
+		receiver.lock.Lock()
+		if primaryErr != nil {
+			receiver.primaryFails++
+			receiver.primaryHealthy = 0
+		} else {
+			receiver.primaryFails = 0
+			receiver.primaryHealthy++
+		}
+		isStandby, primaryFails, primaryHealthy := receiver.isStandby, receiver.primaryFails, receiver.primaryHealthy
+		receiver.lock.Unlock()
+
+		switch {
+		case !isStandby && primaryFails >= receiver.threshold:
+			// 备用集群也不可用时，继续使用主集群
+			standbyRevision, standbyErr := receiver.check(ctx, receiver.standby)
+			if standbyErr != nil {
+				flog.Warningf("Etcd主集群不可用（%s），备用集群也不可用：%s", primaryErr.Error(), standbyErr.Error())
+				continue
+			}
+			flog.Warningf("Etcd主集群连续%d次健康检查失败，切换到备用集群：%s", primaryFails, primaryErr.Error())
+			receiver.switchTo(true, standbyRevision)
+		case isStandby && receiver.backThreshold > 0 && primaryHealthy >= receiver.backThreshold:
+			flog.Infof("Etcd主集群已连续%d次健康检查成功，切回主集群", primaryHealthy)
+			receiver.switchTo(false, primaryRevision)
+		}
+	}
+}
+
+// 线性读需要集群有Leader且多数节点可用，返回集群当前的revision
+func (receiver *failoverState) check(ctx context.Context, cli *client) (int64, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, receiver.interval)
+	defer cancel()
+	rsp, err := cli.connection().Get(checkCtx, "\x00", etcdV3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return rsp.Header.Revision, nil
+}
+
+// 切换集群，并将Watch重新注册到新的集群，revision：健康检查时新集群的revision
+func (receiver *failoverState) switchTo(isStandby bool, revision int64) {
+	receiver.lock.Lock()
+	if receiver.isStandby == isStandby {
+		receiver.lock.Unlock()
+		return
+	}
+	receiver.isStandby = isStandby
+	receiver.primaryFails, receiver.primaryHealthy = 0, 0
+	onFailovers := receiver.onFailovers
+	watches := make([]*failoverWatch, 0, len(receiver.watches))
+	for watch := range receiver.watches {
+		watches = append(watches, watch)
+	}
+	receiver.lock.Unlock()
+
+	for _, watch := range watches {
+		watch.start(revision)
+	}
+
+	from, to := ClusterPrimary, ClusterStandby
+	if !isStandby {
+		from, to = ClusterStandby, ClusterPrimary
+	}
+	for _, onFailover := range onFailovers {
+		onFailover(from, to)
+	}
+}
+
+// 集群在revisions中的下标
+func clusterIndex(isStandby bool) int {
+	if isStandby {
+		return 1
+	}
+	return 0
+}
+
+// 在当前使用的集群上注册Watch（取消之前集群上的Watch），已在当前集群上监听时忽略
+// 从该集群上已处理到的revision之后继续监听，没有处理过该集群的事件时，从baseRevision之后开始（为0时从当前开始）
+func (receiver *failoverWatch) start(baseRevision int64) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	isStandby := receiver.state.isActiveStandby()
+	if receiver.cancel != nil {
+		if receiver.isStandby == isStandby {
+			return
+		}
+		receiver.cancel()
+	}
+	ctx, cancel := context.WithCancel(receiver.ctx)
+	receiver.cancel, receiver.isStandby = cancel, isStandby
+
+	index := clusterIndex(isStandby)
+	if receiver.revisions[index] == 0 {
+		receiver.revisions[index] = baseRevision
+	}
+	var revision int64
+	if receiver.revisions[index] > 0 {
+		revision = receiver.revisions[index] + 1
+	}
+
+	cli := receiver.primary
+	if isStandby {
+		cli = receiver.standby
+	}
+	// 集群不可用时，clientv3的Watch会阻塞到连接成功，异步注册，避免阻塞调用方和切换
+	go watchFrom(cli, ctx, receiver.key, receiver.isPrefix, revision, func(event WatchEvent) {
+		receiver.lock.Lock()
+		// 已切换到其它集群（取消后仍可能收到缓冲的事件），切回时从已处理到的revision之后重新收到
+		if ctx.Err() != nil {
+			receiver.lock.Unlock()
+			return
+		}
+		receiver.revisions[index] = event.Kv.ModRevision
+		receiver.lock.Unlock()
+		receiver.fn(event)
+	})
+}
+
+func (receiver *failoverClient) watch(ctx context.Context, key string, isPrefix bool, revision int64, watchFunc func(event WatchEvent)) {
+	watch := &failoverWatch{ctx: ctx, state: receiver.state, primary: receiver.primary, standby: receiver.standby, key: key, isPrefix: isPrefix, fn: watchFunc}
+
+	// 先加入到切换列表再注册，注册时取当前使用的集群，与切换同时发生时也不会遗漏
+	receiver.state.lock.Lock()
+	receiver.state.watches[watch] = struct{}{}
+	receiver.state.lock.Unlock()
+	var baseRevision int64
+	if revision > 0 {
+		baseRevision = revision - 1
+	}
+	watch.start(baseRevision)
+
+	go func() {
+		<-ctx.Done()
+		receiver.state.lock.Lock()
+		delete(receiver.state.watches, watch)
+		receiver.state.lock.Unlock()
+	}()
+}
+
+func (receiver *failoverClient) Close() {
+	if !receiver.isView {
+		receiver.state.cancel()
+	}
+	receiver.primary.Close()
+	receiver.standby.Close()
+}
+
+func (receiver *failoverClient) Put(key, value string) (*Header, error) {
+	return receiver.active().Put(key, value)
+}
+
+func (receiver *failoverClient) PutLease(key, value string, leaseId LeaseID) (*Header, error) {
+	return receiver.active().PutLease(key, value, leaseId)
+}
+
+func (receiver *failoverClient) PutJson(key string, data any) (*Header, error) {
+	return receiver.active().PutJson(key, data)
+}
+
+func (receiver *failoverClient) PutJsonLease(key string, data any, leaseId LeaseID) (*Header, error) {
+	return receiver.active().PutJsonLease(key, data, leaseId)
+}
+
+func (receiver *failoverClient) Get(key string) (*KeyValue, error) {
+	return receiver.active().Get(key)
+}
+
+func (receiver *failoverClient) GetPrefixKey(prefixKey string) (map[string]*KeyValue, error) {
+	return receiver.active().GetPrefixKey(prefixKey)
+}
+
+func (receiver *failoverClient) Delete(key string) (*Header, error) {
+	return receiver.active().Delete(key)
+}
+
+func (receiver *failoverClient) DeletePrefixKey(prefixKey string) (*Header, error) {
+	return receiver.active().DeletePrefixKey(prefixKey)
+}
+
+func (receiver *failoverClient) Exists(key string) bool {
+	return receiver.active().Exists(key)
+}
+
+func (receiver *failoverClient) Incr(key string, delta int64) (int64, error) {
+	return receiver.active().Incr(key, delta)
+}
+
+func (receiver *failoverClient) Decr(key string, delta int64) (int64, error) {
+	return receiver.active().Decr(key, delta)
+}
+
+func (receiver *failoverClient) Watch(ctx context.Context, key string, watchFunc func(event WatchEvent)) {
+	receiver.watch(ctx, key, false, 0, watchFunc)
+}
+
+func (receiver *failoverClient) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
+	receiver.watch(ctx, prefixKey, true, 0, watchFunc)
+}
+
+func (receiver *failoverClient) LeaseGrant(ttl int64, keys ...string) (LeaseID, error) {
+	return receiver.active().LeaseGrant(ttl, keys...)
+}
+
+func (receiver *failoverClient) LeaseKeepAlive(ctx context.Context, leaseId LeaseID) error {
+	return receiver.active().LeaseKeepAlive(ctx, leaseId)
+}
+
+func (receiver *failoverClient) LeaseKeepAliveOnce(leaseId LeaseID) error {
+	return receiver.active().LeaseKeepAliveOnce(leaseId)
+}
+
+func (receiver *failoverClient) LeaseRevoke(leaseId LeaseID) (*Header, error) {
+	return receiver.active().LeaseRevoke(leaseId)
+}
+
+func (receiver *failoverClient) LeaseInfo(leaseId LeaseID) (*LeaseInfo, error) {
+	return receiver.active().LeaseInfo(leaseId)
+}
+
+func (receiver *failoverClient) Lock(lockKey string, lockTTL int) (UnLock, error) {
+	return receiver.active().Lock(lockKey, lockTTL)
+}
+
+func (receiver *failoverClient) WithNamespace(prefix string) IClient {
+	return &failoverClient{
+		primary: receiver.primary.WithNamespace(prefix),
+		standby: receiver.standby.WithNamespace(prefix),
+		state:   receiver.state,
+		isView:  true,
+	}
+}
+
+func (receiver *failoverClient) Namespace() string {
+	return receiver.primary.Namespace()
+}
+
+func (receiver *failoverClient) Original() *etcdClient {
+	return receiver.active().Original()
+}
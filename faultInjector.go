@@ -0,0 +1,388 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FaultKind 注入的故障类型
+type FaultKind int
+
+const (
+	FaultLatency        FaultKind = iota // 延迟（KV操作、Watch事件）
+	FaultError                           // 返回错误
+	FaultDropWatchEvent                  // 丢弃Watch事件
+	FaultCloseWatch                      // 关闭Watch（之后不再收到事件）
+	FaultExpireLease                     // 租约提前到期（创建租约、KEY赋加租约后，经过Delay撤销租约）
+)
+
+// ErrFaultInjected 故障注入的默认错误
+var ErrFaultInjected = fmt.Errorf("故障注入")
+
+// FaultRule 故障规则
+type FaultRule struct {
+	Kind        FaultKind
+	KeyPattern  string        // KEY的匹配规则，*匹配任意字符（包括/），?匹配一个字符，为空时匹配所有的操作（包括不带KEY的租约操作）
+	Probability float64       // 触发的概率（0~1），<=0或>=1时总是触发
+	Delay       time.Duration // FaultLatency：延迟的时间；FaultExpireLease：多久后到期（0：立即）
+	Err         error         // FaultError：返回的错误（为空时返回ErrFaultInjected）
+}
+
+type faultRule struct {
+	FaultRule
+	id      int
+	pattern *regexp.Regexp // 为空时匹配所有
+}
+
+// FaultInjector 故障注入（用于弹性测试），通过Wrap包装客户端后，按KEY、概率注入延迟、错误、Watch异常、租约到期，可在运行时开启、关闭
+type FaultInjector struct {
+	lock     sync.Mutex
+	disabled bool
+	rules    []*faultRule
+	ruleId   int
+	random   *rand.Rand
+	injected map[FaultKind]int64
+}
+
+// 包装后的客户端
+type faultClient struct {
+	inner    IClient
+	injector *FaultInjector
+}
+
+// NewFaultInjector 创建故障注入（默认开启）
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		injected: make(map[FaultKind]int64),
+	}
+}
+
+// Wrap 包装客户端，通过包装后的客户端调用时注入故障（通过NewSemaphore(client, ...)等函数创建的对象直接使用底层的连接，不注入故障）
+func (receiver *FaultInjector) Wrap(client IClient) IClient {
+	return &faultClient{inner: client, injector: receiver}
+}
+
+// Add 添加规则，返回规则ID（用于Remove）
+func (receiver *FaultInjector) Add(rule FaultRule) int {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.ruleId++
+	item := &faultRule{FaultRule: rule, id: receiver.ruleId}
+	if rule.KeyPattern != "" {
+		pattern := regexp.QuoteMeta(rule.KeyPattern)
+		pattern = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(pattern)
+		item.pattern = regexp.MustCompile("^" + pattern + "$")
+	}
+	receiver.rules = append(receiver.rules, item)
+	return item.id
+}
+
+// Remove 移除规则
+func (receiver *FaultInjector) Remove(ruleId int) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	for index, rule := range receiver.rules {
+		if rule.id == ruleId {
+			receiver.rules = append(receiver.rules[:index], receiver.rules[index+1:]...)
+			return
+		}
+	}
+}
+
+// Clear 移除所有的规则
+func (receiver *FaultInjector) Clear() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.rules = nil
+}
+
+// Enable 开启故障注入
+func (receiver *FaultInjector) Enable() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.disabled = false
+}
+
+// Disable 关闭故障注入（规则保留，包装后的客户端与原客户端行为一致）
+func (receiver *FaultInjector) Disable() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.disabled = true
+}
+
+// IsEnabled 是否开启
+func (receiver *FaultInjector) IsEnabled() bool {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	return !receiver.disabled
+}
+
+// Seed 设置随机数种子（用于复现）
+func (receiver *FaultInjector) Seed(seed int64) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.random = rand.New(rand.NewSource(seed))
+}
+
+// Injected 已注入的故障次数
+func (receiver *FaultInjector) Injected(kind FaultKind) int64 {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	return receiver.injected[kind]
+}
+
+// 找到匹配的规则，并按概率判断是否触发（没有KEY时只匹配KeyPattern为空的规则）
+func (receiver *FaultInjector) trigger(kind FaultKind, keys ...string) *faultRule {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.disabled {
+		return nil
+	}
+	for _, rule := range receiver.rules {
+		if rule.Kind != kind || !rule.match(keys) {
+			continue
+		}
+		if rule.Probability > 0 && rule.Probability < 1 && receiver.random.Float64() >= rule.Probability {
+			continue
+		}
+		receiver.injected[kind]++
+		return rule
+	}
+	return nil
+}
+
+func (receiver *faultRule) match(keys []string) bool {
+	if receiver.pattern == nil {
+		return true
+	}
+	for _, key := range keys {
+		if receiver.pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// 执行操作前注入延迟、错误
+func (receiver *FaultInjector) before(keys ...string) error {
+	if rule := receiver.trigger(FaultLatency, keys...); rule != nil {
+		time.Sleep(rule.Delay)
+	}
+	if rule := receiver.trigger(FaultError, keys...); rule != nil {
+		if rule.Err != nil {
+			return rule.Err
+		}
+		return ErrFaultInjected
+	}
+	return nil
+}
+
+// 租约提前到期：经过Delay后撤销租约
+func (receiver *faultClient) expireLease(leaseId LeaseID, keys ...string) {
+	rule := receiver.injector.trigger(FaultExpireLease, keys...)
+	if rule == nil {
+		return
+	}
+	time.AfterFunc(rule.Delay, func() {
+		_, _ = receiver.inner.LeaseRevoke(leaseId)
+	})
+}
+
+// 包装监听函数，注入丢弃事件、关闭Watch、延迟
+func (receiver *faultClient) watch(ctx context.Context, watchFunc func(event WatchEvent), watch func(ctx context.Context, watchFunc func(event WatchEvent))) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	var closed int32
+	watch(watchCtx, func(event WatchEvent) {
+		if atomic.LoadInt32(&closed) == 1 {
+			return
+		}
+		if receiver.injector.trigger(FaultCloseWatch, event.Kv.Key) != nil {
+			atomic.StoreInt32(&closed, 1)
+			cancel()
+			return
+		}
+		if receiver.injector.trigger(FaultDropWatchEvent, event.Kv.Key) != nil {
+			return
+		}
+		if rule := receiver.injector.trigger(FaultLatency, event.Kv.Key); rule != nil {
+			time.Sleep(rule.Delay)
+		}
+		watchFunc(event)
+	})
+}
+
+// 被包装的客户端
+func (receiver *faultClient) unwrap() IClient {
+	return receiver.inner
+}
+
+func (receiver *faultClient) Close() {
+	receiver.inner.Close()
+}
+
+func (receiver *faultClient) Put(key, value string) (*Header, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Put(key, value)
+}
+
+func (receiver *faultClient) PutLease(key, value string, leaseId LeaseID) (*Header, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	header, err := receiver.inner.PutLease(key, value, leaseId)
+	if err == nil {
+		receiver.expireLease(leaseId, key)
+	}
+	return header, err
+}
+
+func (receiver *faultClient) PutJson(key string, data any) (*Header, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	return receiver.inner.PutJson(key, data)
+}
+
+func (receiver *faultClient) PutJsonLease(key string, data any, leaseId LeaseID) (*Header, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	header, err := receiver.inner.PutJsonLease(key, data, leaseId)
+	if err == nil {
+		receiver.expireLease(leaseId, key)
+	}
+	return header, err
+}
+
+func (receiver *faultClient) Get(key string) (*KeyValue, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Get(key)
+}
+
+func (receiver *faultClient) GetPrefixKey(prefixKey string) (map[string]*KeyValue, error) {
+	if err := receiver.injector.before(prefixKey); err != nil {
+		return nil, err
+	}
+	return receiver.inner.GetPrefixKey(prefixKey)
+}
+
+func (receiver *faultClient) Delete(key string) (*Header, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Delete(key)
+}
+
+func (receiver *faultClient) DeletePrefixKey(prefixKey string) (*Header, error) {
+	if err := receiver.injector.before(prefixKey); err != nil {
+		return nil, err
+	}
+	return receiver.inner.DeletePrefixKey(prefixKey)
+}
+
+// Exists 注入错误时返回false
+func (receiver *faultClient) Exists(key string) bool {
+	if err := receiver.injector.before(key); err != nil {
+		return false
+	}
+	return receiver.inner.Exists(key)
+}
+
+func (receiver *faultClient) Incr(key string, delta int64) (int64, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return 0, err
+	}
+	return receiver.inner.Incr(key, delta)
+}
+
+func (receiver *faultClient) Decr(key string, delta int64) (int64, error) {
+	if err := receiver.injector.before(key); err != nil {
+		return 0, err
+	}
+	return receiver.inner.Decr(key, delta)
+}
+
+func (receiver *faultClient) Watch(ctx context.Context, key string, watchFunc func(event WatchEvent)) {
+	receiver.watch(ctx, watchFunc, func(ctx context.Context, watchFunc func(event WatchEvent)) {
+		receiver.inner.Watch(ctx, key, watchFunc)
+	})
+}
+
+func (receiver *faultClient) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
+	receiver.watch(ctx, watchFunc, func(ctx context.Context, watchFunc func(event WatchEvent)) {
+		receiver.inner.WatchPrefixKey(ctx, prefixKey, watchFunc)
+	})
+}
+
+func (receiver *faultClient) LeaseGrant(ttl int64, keys ...string) (LeaseID, error) {
+	if err := receiver.injector.before(keys...); err != nil {
+		return 0, err
+	}
+	leaseId, err := receiver.inner.LeaseGrant(ttl, keys...)
+	if err == nil {
+		receiver.expireLease(leaseId, keys...)
+	}
+	return leaseId, err
+}
+
+func (receiver *faultClient) LeaseKeepAlive(ctx context.Context, leaseId LeaseID) error {
+	if err := receiver.injector.before(); err != nil {
+		return err
+	}
+	return receiver.inner.LeaseKeepAlive(ctx, leaseId)
+}
+
+func (receiver *faultClient) LeaseKeepAliveOnce(leaseId LeaseID) error {
+	if err := receiver.injector.before(); err != nil {
+		return err
+	}
+	return receiver.inner.LeaseKeepAliveOnce(leaseId)
+}
+
+func (receiver *faultClient) LeaseRevoke(leaseId LeaseID) (*Header, error) {
+	if err := receiver.injector.before(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.LeaseRevoke(leaseId)
+}
+
+func (receiver *faultClient) LeaseInfo(leaseId LeaseID) (*LeaseInfo, error) {
+	if err := receiver.injector.before(); err != nil {
+		return nil, err
+	}
+	return receiver.inner.LeaseInfo(leaseId)
+}
+
+func (receiver *faultClient) Lock(lockKey string, lockTTL int) (UnLock, error) {
+	if err := receiver.injector.before(lockKey); err != nil {
+		return nil, err
+	}
+	return receiver.inner.Lock(lockKey, lockTTL)
+}
+
+// WithNamespace 命名空间客户端使用同一个故障注入（KEY不含命名空间）
+func (receiver *faultClient) WithNamespace(prefix string) IClient {
+	return &faultClient{inner: receiver.inner.WithNamespace(prefix), injector: receiver.injector}
+}
+
+func (receiver *faultClient) Namespace() string {
+	return receiver.inner.Namespace()
+}
+
+func (receiver *faultClient) Original() *etcdClient {
+	return receiver.inner.Original()
+}
@@ -0,0 +1,161 @@
+package etcd
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// CacheStats Get本地缓存的统计
+type CacheStats struct {
+	Hits          int64 // 命中次数
+	Misses        int64 // 未命中次数
+	Evictions     int64 // 超出容量被淘汰的次数
+	Invalidations int64 // KEY变化或过期导致失效的次数
+	Size          int   // 当前缓存的KEY数量
+}
+
+// Get的本地缓存（LRU），每个缓存的KEY都会Watch，KEY有变化时立即失效
+type getCache struct {
+	etcdCli       *etcdClient
+	size          int           // 最多缓存的KEY数量
+	ttl           time.Duration // 缓存时间，0：不过期
+	lock          sync.Mutex
+	lru           *list.List // 最近使用的在前面
+	items         map[string]*list.Element
+	hits          int64
+	misses        int64
+	evictions     int64
+	invalidations int64
+}
+
+type getCacheEntry struct {
+	kv       *KeyValue
+	expireAt time.Time
+	cancel   context.CancelFunc // 取消Watch
+}
+
+func newGetCache(etcdCli *etcdClient, size int, ttl time.Duration) *getCache {
+	return &getCache{
+		etcdCli: etcdCli,
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// 读取缓存
+func (receiver *getCache) get(key string) (*KeyValue, bool) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	element, exists := receiver.items[key]
+	if exists {
+		entry := element.Value.(*getCacheEntry)
+		if receiver.ttl == 0 || time.Now().Before(entry.expireAt) {
+			receiver.lru.MoveToFront(element)
+			atomic.AddInt64(&receiver.hits, 1)
+			kv := *entry.kv
+			return &kv, true
+		}
+		receiver.removeElement(element)
+		atomic.AddInt64(&receiver.invalidations, 1)
+	}
+	atomic.AddInt64(&receiver.misses, 1)
+	return nil, false
+}
+
+// 写入缓存，并从kv的Revision开始监听KEY的变化
+func (receiver *getCache) set(key string, kv *KeyValue) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if element, exists := receiver.items[key]; exists {
+		receiver.removeElement(element)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &getCacheEntry{kv: kv, expireAt: time.Now().Add(receiver.ttl), cancel: cancel}
+	receiver.items[key] = receiver.lru.PushFront(entry)
+
+	// 超出容量，淘汰最久未使用的
+	for receiver.lru.Len() > receiver.size {
+		receiver.removeElement(receiver.lru.Back())
+		atomic.AddInt64(&receiver.evictions, 1)
+	}
+
+	watchChan := receiver.etcdCli.Watch(ctx, key, etcdV3.WithRev(kv.Header.Revision+1))
+	go func() {
+		// 有任何变化（或Watch中断）时，缓存失效
+		<-watchChan
+		cancel()
+		receiver.lock.Lock()
+		defer receiver.lock.Unlock()
+		if current, exists := receiver.items[key]; exists && current.Value == entry {
+			receiver.removeElement(current)
+			atomic.AddInt64(&receiver.invalidations, 1)
+		}
+	}()
+}
+
+// 删除缓存
+func (receiver *getCache) remove(key string) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if element, exists := receiver.items[key]; exists {
+		receiver.removeElement(element)
+		atomic.AddInt64(&receiver.invalidations, 1)
+	}
+}
+
+// 根据KEY前缀删除缓存
+func (receiver *getCache) removePrefix(prefixKey string) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	for key, element := range receiver.items {
+		if strings.HasPrefix(key, prefixKey) {
+			receiver.removeElement(element)
+			atomic.AddInt64(&receiver.invalidations, 1)
+		}
+	}
+}
+
+// 清空缓存，并取消所有的Watch
+func (receiver *getCache) clear() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	for _, element := range receiver.items {
+		receiver.removeElement(element)
+	}
+}
+
+func (receiver *getCache) stats() CacheStats {
+	receiver.lock.Lock()
+	size := receiver.lru.Len()
+	receiver.lock.Unlock()
+
+	return CacheStats{
+		Hits:          atomic.LoadInt64(&receiver.hits),
+		Misses:        atomic.LoadInt64(&receiver.misses),
+		Evictions:     atomic.LoadInt64(&receiver.evictions),
+		Invalidations: atomic.LoadInt64(&receiver.invalidations),
+		Size:          size,
+	}
+}
+
+// 需在加锁后调用
+func (receiver *getCache) removeElement(element *list.Element) {
+	entry := element.Value.(*getCacheEntry)
+	entry.cancel()
+	receiver.lru.Remove(element)
+	delete(receiver.items, entry.kv.Key)
+}
@@ -0,0 +1,41 @@
+package etcd
+
+import "context"
+
+// IAuth 认证与权限（RBAC）管理
+type IAuth interface {
+	// AuthEnable 开启认证（需先创建root用户）
+	AuthEnable(ctx context.Context) error
+	// AuthDisable 关闭认证
+	AuthDisable(ctx context.Context) error
+	// AuthStatus 是否开启了认证
+	AuthStatus(ctx context.Context) (bool, error)
+	// UserAdd 添加用户
+	UserAdd(ctx context.Context, name string, password string) error
+	// UserDelete 删除用户
+	UserDelete(ctx context.Context, name string) error
+	// UserChangePassword 修改用户密码
+	UserChangePassword(ctx context.Context, name string, password string) error
+	// UserGrantRole 给用户授予角色
+	UserGrantRole(ctx context.Context, name string, role string) error
+	// UserRevokeRole 撤销用户的角色
+	UserRevokeRole(ctx context.Context, name string, role string) error
+	// UserGet 获取用户拥有的角色
+	UserGet(ctx context.Context, name string) (*User, error)
+	// UserList 所有的用户名
+	UserList(ctx context.Context) ([]string, error)
+	// RoleAdd 添加角色
+	RoleAdd(ctx context.Context, name string) error
+	// RoleDelete 删除角色
+	RoleDelete(ctx context.Context, name string) error
+	// RoleGet 获取角色拥有的权限
+	RoleGet(ctx context.Context, name string) (*Role, error)
+	// RoleList 所有的角色名
+	RoleList(ctx context.Context) ([]string, error)
+	// RoleGrantPermission 给角色授予KEY（范围、前缀）的权限
+	RoleGrantPermission(ctx context.Context, name string, permission Permission) error
+	// RoleRevokePermission 撤销角色对KEY（范围、前缀）的权限
+	RoleRevokePermission(ctx context.Context, name string, permission Permission) error
+	// ApplyPolicy 将用户、角色、权限调整为与policy一致，返回执行（DryRun时为将要执行）的变更
+	ApplyPolicy(ctx context.Context, policy RBACPolicy) ([]string, error)
+}
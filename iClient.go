@@ -2,6 +2,9 @@ package etcd
 
 import "context"
 
+// IClient 客户端（KV、Watch、租约、锁）
+// 信号量、屏障、队列、序列号、Mirror、连接监控、运维、认证、备份、导出导入、自动压缩等通过NewSemaphore(client, ...)等函数创建
+// 配置了备用集群时，这些函数使用创建时正在使用的集群，切换集群后不会跟随切换（信号量、队列、Mirror、定时备份、自动压缩、跨集群复制需要在OnFailover中重新创建）
 type IClient interface {
 	// Close 关闭客户端
 	Close()
@@ -23,6 +26,10 @@ type IClient interface {
 	DeletePrefixKey(prefixKey string) (*Header, error)
 	// Exists 判断是否存在KEY
 	Exists(key string) bool
+	// Incr 对KEY的值加上delta（KEY不存在时从0开始），返回加上后的值
+	Incr(key string, delta int64) (int64, error)
+	// Decr 对KEY的值减去delta（KEY不存在时从0开始），返回减去后的值
+	Decr(key string, delta int64) (int64, error)
 	// Watch 监听KEY
 	Watch(ctx context.Context, key string, watchFunc func(event WatchEvent))
 	// WatchPrefixKey 根据KEY前缀来监听
@@ -39,6 +46,10 @@ type IClient interface {
 	LeaseInfo(leaseId LeaseID) (*LeaseInfo, error)
 	// Lock 添加锁
 	Lock(lockKey string, lockTTL int) (UnLock, error)
+	// WithNamespace 创建带KEY前缀（命名空间）的客户端，与当前客户端共用连接
+	WithNamespace(prefix string) IClient
+	// Namespace 当前客户端的KEY前缀
+	Namespace() string
 	// Original 原客户端对象
 	Original() *etcdClient
 }
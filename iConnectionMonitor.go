@@ -0,0 +1,15 @@
+package etcd
+
+// IConnectionMonitor 连接监控（连接状态、当前连接的节点、集群Leader）
+type IConnectionMonitor interface {
+	// ConnectionState 当前的连接状态
+	ConnectionState() ConnectionState
+	// CurrentEndpoint 当前连接的节点地址（首次检测完成前为空）
+	CurrentEndpoint() string
+	// Leader 集群Leader的节点ID（首次检测完成前为0）
+	Leader() uint64
+	// OnConnectionStateChange 订阅连接状态的变化
+	OnConnectionStateChange(fn func(old ConnectionState, new ConnectionState))
+	// OnLeaderChange 订阅集群Leader的变化
+	OnLeaderChange(fn func(old uint64, new uint64))
+}
@@ -0,0 +1,29 @@
+package etcd
+
+import "context"
+
+// IMaintenance 集群运维（节点、告警、碎片整理、压缩等）
+type IMaintenance interface {
+	// MemberList 集群的所有节点
+	MemberList(ctx context.Context) ([]Member, error)
+	// MemberAdd 添加节点，peerURLs：节点间通讯地址，isLearner：是否作为learner加入（不参与投票）
+	MemberAdd(ctx context.Context, peerURLs []string, isLearner bool) (*Member, error)
+	// MemberRemove 移除节点
+	MemberRemove(ctx context.Context, memberId uint64) error
+	// MemberPromote 将learner提升为可投票的节点
+	MemberPromote(ctx context.Context, memberId uint64) error
+	// EndpointStatus 查询节点状态
+	EndpointStatus(ctx context.Context, endpoint string) (*EndpointStatus, error)
+	// AlarmList 集群的所有告警
+	AlarmList(ctx context.Context) ([]Alarm, error)
+	// AlarmDisarm 解除告警
+	AlarmDisarm(ctx context.Context, alarm Alarm) error
+	// Defragment 对节点进行碎片整理（期间该节点会阻塞读写）
+	Defragment(ctx context.Context, endpoint string) error
+	// Compact 压缩revision之前的历史版本，physical：等待压缩在物理上完成后再返回
+	Compact(ctx context.Context, revision int64, physical bool) error
+	// HashKV 计算节点在revision（0：最新）时的KV哈希，用于检测节点间数据是否一致
+	HashKV(ctx context.Context, endpoint string, revision int64) (*HashKV, error)
+	// MoveLeader 将Leader转移到指定的节点
+	MoveLeader(ctx context.Context, transfereeId uint64) error
+}
@@ -0,0 +1,206 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/farseer-go/fs/trace"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// Member 集群节点
+type Member struct {
+	ID         uint64   // 节点ID
+	Name       string   // 节点名称（未启动的节点为空）
+	PeerURLs   []string // 节点间通讯地址
+	ClientURLs []string // 客户端地址
+	IsLearner  bool     // 是否为learner
+}
+
+// Alarm 告警
+type Alarm struct {
+	MemberId uint64 // 节点ID
+	Type     string // 告警类型：NOSPACE、CORRUPT
+}
+
+// HashKV 节点的KV哈希
+type HashKV struct {
+	Header          *Header
+	Hash            uint32 // KV哈希
+	CompactRevision int64  // 计算哈希时的压缩版本
+	HashRevision    int64  // 计算哈希时的版本
+}
+
+type maintenance struct {
+	etcdCli      *etcdClient // 原始连接（不带KEY前缀）
+	traceManager trace.IManager
+}
+
+// NewMaintenance 集群运维（节点、告警、碎片整理、压缩等，不受命名空间影响）
+func NewMaintenance(client IClient) IMaintenance {
+	cli := clientOf(client)
+	return &maintenance{etcdCli: cli.connection(), traceManager: cli.traceManager}
+}
+
+func (receiver *maintenance) MemberList(ctx context.Context) ([]Member, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MemberList", "", 0)
+	rsp, err := receiver.etcdCli.MemberList(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	members := make([]Member, 0, len(rsp.Members))
+	for _, member := range rsp.Members {
+		members = append(members, newMember(member))
+	}
+	return members, nil
+}
+
+func (receiver *maintenance) MemberAdd(ctx context.Context, peerURLs []string, isLearner bool) (*Member, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MemberAdd", "", 0)
+	var rsp *etcdV3.MemberAddResponse
+	var err error
+	if isLearner {
+		rsp, err = receiver.etcdCli.MemberAddAsLearner(ctx, peerURLs)
+	} else {
+		rsp, err = receiver.etcdCli.MemberAdd(ctx, peerURLs)
+	}
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	member := newMember(rsp.Member)
+	return &member, nil
+}
+
+func (receiver *maintenance) MemberRemove(ctx context.Context, memberId uint64) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MemberRemove", "", 0)
+	_, err := receiver.etcdCli.MemberRemove(ctx, memberId)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *maintenance) MemberPromote(ctx context.Context, memberId uint64) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MemberPromote", "", 0)
+	_, err := receiver.etcdCli.MemberPromote(ctx, memberId)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *maintenance) EndpointStatus(ctx context.Context, endpoint string) (*EndpointStatus, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("EndpointStatus", endpoint, 0)
+	startAt := time.Now()
+	rsp, err := receiver.etcdCli.Status(ctx, endpoint)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	status := newEndpointStatus(endpoint, rsp, time.Since(startAt))
+	return &status, nil
+}
+
+func (receiver *maintenance) AlarmList(ctx context.Context) ([]Alarm, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AlarmList", "", 0)
+	rsp, err := receiver.etcdCli.AlarmList(ctx)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	alarms := make([]Alarm, 0, len(rsp.Alarms))
+	for _, alarm := range rsp.Alarms {
+		alarms = append(alarms, Alarm{MemberId: alarm.MemberID, Type: alarm.Alarm.String()})
+	}
+	return alarms, nil
+}
+
+func (receiver *maintenance) AlarmDisarm(ctx context.Context, alarm Alarm) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("AlarmDisarm", alarm.Type, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	alarmType, exists := pb.AlarmType_value[alarm.Type]
+	if !exists {
+		err = fmt.Errorf("告警类型不正确：%s", alarm.Type)
+		return err
+	}
+	_, err = receiver.etcdCli.AlarmDisarm(ctx, &etcdV3.AlarmMember{MemberID: alarm.MemberId, Alarm: pb.AlarmType(alarmType)})
+	return err
+}
+
+func (receiver *maintenance) Defragment(ctx context.Context, endpoint string) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Defragment", endpoint, 0)
+	_, err := receiver.etcdCli.Defragment(ctx, endpoint)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *maintenance) Compact(ctx context.Context, revision int64, physical bool) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("Compact", "", 0)
+	var opts []etcdV3.CompactOption
+	if physical {
+		opts = append(opts, etcdV3.WithCompactPhysical())
+	}
+	_, err := receiver.etcdCli.Compact(ctx, revision, opts...)
+	defer func() { traceDetailEtcd.End(err) }()
+	return err
+}
+
+func (receiver *maintenance) HashKV(ctx context.Context, endpoint string, revision int64) (*HashKV, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("HashKV", endpoint, 0)
+	rsp, err := receiver.etcdCli.HashKV(ctx, endpoint, revision)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	return &HashKV{
+		Header:          newResponse(rsp.Header),
+		Hash:            rsp.Hash,
+		CompactRevision: rsp.CompactRevision,
+		HashRevision:    rsp.HashRevision,
+	}, nil
+}
+
+func (receiver *maintenance) MoveLeader(ctx context.Context, transfereeId uint64) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MoveLeader", "", 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	// 只有Leader能处理转移请求，所以需要直接连接到Leader节点
+	leaderEndpoint := ""
+	for _, endpoint := range receiver.etcdCli.Endpoints() {
+		if rsp, statusErr := receiver.etcdCli.Status(ctx, endpoint); statusErr == nil && rsp.Leader == rsp.Header.MemberId {
+			leaderEndpoint = endpoint
+			break
+		}
+	}
+	if leaderEndpoint == "" {
+		err = fmt.Errorf("配置的节点中没有找到Leader：%v", receiver.etcdCli.Endpoints())
+		return err
+	}
+
+	conn, err := receiver.etcdCli.Dial(leaderEndpoint)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, err = etcdV3.NewMaintenanceFromMaintenanceClient(pb.NewMaintenanceClient(conn), receiver.etcdCli).MoveLeader(ctx, transfereeId)
+	return err
+}
+
+func newMember(member *pb.Member) Member {
+	return Member{
+		ID:         member.ID,
+		Name:       member.Name,
+		PeerURLs:   member.PeerURLs,
+		ClientURLs: member.ClientURLs,
+		IsLearner:  member.IsLearner,
+	}
+}
@@ -0,0 +1,107 @@
+package etcd
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/farseer-go/fs/container"
+	"github.com/farseer-go/fs/trace"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// 内存连接的缓冲区大小
+const memoryBufferSize = 1024 * 1024
+
+// MemoryEtcd 内存中的etcd（用于单元测试，不需要etcd服务端）
+// 模拟了Revision、Version、租约（使用模拟的时钟，通过Advance过期）、Watch（进度通知同样使用模拟的时钟）、事务、压缩，客户端与连接etcd时走同样的代码
+type MemoryEtcd struct {
+	store    *memoryStore
+	server   *grpc.Server
+	listener *bufconn.Listener
+}
+
+// NewMemoryEtcd 创建内存中的etcd
+func NewMemoryEtcd() *MemoryEtcd {
+	store := newMemoryStore()
+	server := grpc.NewServer()
+	pb.RegisterKVServer(server, &memoryKVServer{store: store})
+	pb.RegisterWatchServer(server, &memoryWatchServer{store: store})
+	pb.RegisterLeaseServer(server, &memoryLeaseServer{store: store})
+	pb.RegisterClusterServer(server, &memoryClusterServer{store: store})
+	pb.RegisterMaintenanceServer(server, &memoryMaintenanceServer{store: store})
+
+	listener := bufconn.Listen(memoryBufferSize)
+	go func() { _ = server.Serve(listener) }()
+	return &MemoryEtcd{store: store, server: server, listener: listener}
+}
+
+// RegisterMemory 创建内存中的etcd，并以name注册到容器（替换已有的注册），业务代码不需要修改
+func RegisterMemory(name string) *MemoryEtcd {
+	memory := NewMemoryEtcd()
+	memory.Register(name)
+	return memory
+}
+
+// Register 以name注册到容器（替换已有的注册），与Register一样同一个名称共用一个连接，Close时只释放引用
+func (receiver *MemoryEtcd) Register(name string) {
+	registerPool(name, newClientPool(name, receiver.Client))
+}
+
+// Client 创建一个连接到内存etcd的客户端
+func (receiver *MemoryEtcd) Client() (IClient, error) {
+	conn, err := grpc.NewClient("passthrough:///"+memoryEndpoint,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return receiver.listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := etcdV3.NewCtxClient(context.Background())
+	cli.KV = etcdV3.NewKVFromKVClient(pb.NewKVClient(conn), cli)
+	cli.Watcher = etcdV3.NewWatchFromWatchClient(pb.NewWatchClient(conn), cli)
+	cli.Lease = etcdV3.NewLeaseFromLeaseClient(pb.NewLeaseClient(conn), cli, time.Second)
+	cli.Cluster = etcdV3.NewClusterFromClusterClient(pb.NewClusterClient(conn), cli)
+	cli.Maintenance = etcdV3.NewMaintenanceFromMaintenanceClient(pb.NewMaintenanceClient(conn), cli)
+	cli.Auth = etcdV3.NewAuthFromAuthClient(pb.NewAuthClient(conn), cli)
+	// 客户端关闭时，关闭连接
+	go func() {
+		<-cli.Ctx().Done()
+		_ = conn.Close()
+	}()
+
+	// 内存连接不会断开，也只有一个节点
+	monitor := newConnectionMonitor(nil, 0)
+	monitor.state, monitor.endpoint, monitor.leader = ConnectionStateReady, memoryEndpoint, memoryMemberId
+	return &client{etcdCli: cli, traceManager: container.Resolve[trace.IManager](), monitor: monitor, memoryConn: conn}, nil
+}
+
+// Advance 时钟前进d，到期的租约会被删除（关联的KEY同时删除）
+func (receiver *MemoryEtcd) Advance(d time.Duration) {
+	receiver.store.advance(d)
+}
+
+// Now 模拟时钟的当前时间
+func (receiver *MemoryEtcd) Now() time.Time {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+	return receiver.store.now
+}
+
+// Revision 当前的Revision
+func (receiver *MemoryEtcd) Revision() int64 {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+	return receiver.store.revision
+}
+
+// Close 停止内存etcd（所有的连接都会断开）
+func (receiver *MemoryEtcd) Close() {
+	receiver.server.Stop()
+	_ = receiver.listener.Close()
+}
@@ -0,0 +1,230 @@
+package etcd
+
+import (
+	"context"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/version"
+)
+
+// 内存集群的节点地址
+const memoryEndpoint = "memory://etcd"
+
+// 内存集群的KV服务
+type memoryKVServer struct {
+	store *memoryStore
+}
+
+func (receiver *memoryKVServer) Range(ctx context.Context, request *pb.RangeRequest) (*pb.RangeResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	rsp, err := receiver.store.doRange(request)
+	if err != nil {
+		return nil, err
+	}
+	rsp.Header = receiver.store.header()
+	return rsp, nil
+}
+
+func (receiver *memoryKVServer) Put(ctx context.Context, request *pb.PutRequest) (*pb.PutResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	txn := receiver.store.begin()
+	rsp, err := receiver.store.doPut(txn, request)
+	if err != nil {
+		return nil, err
+	}
+	receiver.store.commit(txn)
+	rsp.Header = receiver.store.header()
+	return rsp, nil
+}
+
+func (receiver *memoryKVServer) DeleteRange(ctx context.Context, request *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	txn := receiver.store.begin()
+	rsp := receiver.store.doDeleteRange(txn, request)
+	receiver.store.commit(txn)
+	rsp.Header = receiver.store.header()
+	return rsp, nil
+}
+
+func (receiver *memoryKVServer) Txn(ctx context.Context, request *pb.TxnRequest) (*pb.TxnResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	// 与etcd一致，事务要么全部生效，要么全部不生效
+	txn := receiver.store.begin()
+	rsp, err := receiver.store.doTxn(txn, request)
+	if err != nil {
+		receiver.store.rollback(txn)
+		return nil, err
+	}
+	receiver.store.commit(txn)
+	setTxnHeader(rsp, receiver.store.header())
+	return rsp, nil
+}
+
+func (receiver *memoryKVServer) Compact(ctx context.Context, request *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	if err := receiver.store.compact(request.Revision); err != nil {
+		return nil, err
+	}
+	return &pb.CompactionResponse{Header: receiver.store.header()}, nil
+}
+
+// 内存集群的租约服务
+type memoryLeaseServer struct {
+	store *memoryStore
+}
+
+func (receiver *memoryLeaseServer) LeaseGrant(ctx context.Context, request *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	lease, err := receiver.store.grant(request.ID, request.TTL)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LeaseGrantResponse{Header: receiver.store.header(), ID: lease.id, TTL: lease.ttl}, nil
+}
+
+func (receiver *memoryLeaseServer) LeaseRevoke(ctx context.Context, request *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	if err := receiver.store.revoke(request.ID); err != nil {
+		return nil, err
+	}
+	return &pb.LeaseRevokeResponse{Header: receiver.store.header()}, nil
+}
+
+func (receiver *memoryLeaseServer) LeaseKeepAlive(server pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		request, err := server.Recv()
+		if err != nil {
+			return nil
+		}
+
+		receiver.store.lock.Lock()
+		rsp := &pb.LeaseKeepAliveResponse{Header: receiver.store.header(), ID: request.ID}
+		// 租约不存在时TTL为0
+		if lease := receiver.store.leases[request.ID]; lease != nil {
+			lease.expireAt = receiver.store.now.Add(time.Duration(lease.ttl) * time.Second)
+			rsp.TTL = lease.ttl
+		}
+		receiver.store.lock.Unlock()
+
+		if err = server.Send(rsp); err != nil {
+			return nil
+		}
+	}
+}
+
+func (receiver *memoryLeaseServer) LeaseTimeToLive(ctx context.Context, request *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	// 与etcd一致：租约不存在时TTL为-1
+	lease := receiver.store.leases[request.ID]
+	if lease == nil {
+		return &pb.LeaseTimeToLiveResponse{Header: receiver.store.header(), ID: request.ID, TTL: -1}, nil
+	}
+	rsp := &pb.LeaseTimeToLiveResponse{Header: receiver.store.header(), ID: lease.id, TTL: receiver.store.remaining(lease), GrantedTTL: lease.ttl}
+	if request.Keys {
+		for key := range lease.keys {
+			rsp.Keys = append(rsp.Keys, []byte(key))
+		}
+	}
+	return rsp, nil
+}
+
+func (receiver *memoryLeaseServer) LeaseLeases(ctx context.Context, request *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	rsp := &pb.LeaseLeasesResponse{Header: receiver.store.header()}
+	for id := range receiver.store.leases {
+		rsp.Leases = append(rsp.Leases, &pb.LeaseStatus{ID: id})
+	}
+	return rsp, nil
+}
+
+// 内存集群的节点服务（只有一个节点，不支持增删节点）
+type memoryClusterServer struct {
+	pb.UnimplementedClusterServer
+	store *memoryStore
+}
+
+func (receiver *memoryClusterServer) MemberList(ctx context.Context, request *pb.MemberListRequest) (*pb.MemberListResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	return &pb.MemberListResponse{
+		Header:  receiver.store.header(),
+		Members: []*pb.Member{{ID: memoryMemberId, Name: "memory", ClientURLs: []string{memoryEndpoint}}},
+	}, nil
+}
+
+// 内存集群的运维服务（不支持快照、迁移Leader）
+type memoryMaintenanceServer struct {
+	pb.UnimplementedMaintenanceServer
+	store *memoryStore
+}
+
+func (receiver *memoryMaintenanceServer) Alarm(ctx context.Context, request *pb.AlarmRequest) (*pb.AlarmResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+	return &pb.AlarmResponse{Header: receiver.store.header()}, nil
+}
+
+func (receiver *memoryMaintenanceServer) Status(ctx context.Context, request *pb.StatusRequest) (*pb.StatusResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	size := receiver.store.size()
+	return &pb.StatusResponse{
+		Header:           receiver.store.header(),
+		Version:          version.Version,
+		DbSize:           size,
+		DbSizeInUse:      size,
+		Leader:           memoryMemberId,
+		RaftIndex:        uint64(receiver.store.revision),
+		RaftTerm:         1,
+		RaftAppliedIndex: uint64(receiver.store.revision),
+	}, nil
+}
+
+func (receiver *memoryMaintenanceServer) Defragment(ctx context.Context, request *pb.DefragmentRequest) (*pb.DefragmentResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+	return &pb.DefragmentResponse{Header: receiver.store.header()}, nil
+}
+
+func (receiver *memoryMaintenanceServer) Hash(ctx context.Context, request *pb.HashRequest) (*pb.HashResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+	return &pb.HashResponse{Header: receiver.store.header(), Hash: receiver.store.hash(0)}, nil
+}
+
+func (receiver *memoryMaintenanceServer) HashKV(ctx context.Context, request *pb.HashKVRequest) (*pb.HashKVResponse, error) {
+	receiver.store.lock.Lock()
+	defer receiver.store.lock.Unlock()
+
+	revision := request.Revision
+	if revision == 0 {
+		revision = receiver.store.revision
+	}
+	return &pb.HashKVResponse{
+		Header:          receiver.store.header(),
+		Hash:            receiver.store.hash(revision),
+		CompactRevision: receiver.store.compacted,
+		HashRevision:    revision,
+	}, nil
+}
@@ -0,0 +1,534 @@
+package etcd
+
+import (
+	"bytes"
+	"hash/crc32"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// 内存集群的节点ID、集群ID
+const memoryMemberId uint64 = 1
+const memoryClusterId uint64 = 1
+
+// 内存中的MVCC存储：模拟etcd的Revision、Version、租约、Watch、事务
+type memoryStore struct {
+	lock      sync.Mutex
+	revision  int64                         // 当前的Revision
+	compacted int64                         // 已压缩到的Revision
+	keys      map[string][]*mvccpb.KeyValue // 每个KEY的历史版本（按Revision递增，删除时为墓碑：CreateRevision=0）
+	events    []*mvccpb.Event               // 所有的变化（按Revision递增，用于从历史Revision开始Watch）
+	leases    map[int64]*memoryLease        // 租约
+	leaseId   int64                         // 最后分配的租约ID
+	watchers  map[*memoryWatcher]struct{}   // Watch
+	now       time.Time                     // 模拟的时钟（只有Advance时才会前进）
+}
+
+// 租约
+type memoryLease struct {
+	id       int64
+	ttl      int64
+	expireAt time.Time
+	keys     map[string]struct{}
+}
+
+// 一次写事务：所有的变化使用同一个Revision
+type memoryTxn struct {
+	revision int64
+	events   []*mvccpb.Event
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		revision: 1, // 与etcd一致，新集群的Revision从1开始
+		keys:     make(map[string][]*mvccpb.KeyValue),
+		leases:   make(map[int64]*memoryLease),
+		watchers: make(map[*memoryWatcher]struct{}),
+		now:      time.Unix(0, 0),
+	}
+}
+
+func (receiver *memoryStore) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: memoryClusterId, MemberId: memoryMemberId, Revision: receiver.revision, RaftTerm: 1}
+}
+
+// 开始写事务（需在加锁后调用）
+func (receiver *memoryStore) begin() *memoryTxn {
+	return &memoryTxn{revision: receiver.revision + 1}
+}
+
+// 提交写事务，并通知Watch（需在加锁后调用）
+func (receiver *memoryStore) commit(txn *memoryTxn) {
+	if len(txn.events) == 0 {
+		return
+	}
+	receiver.revision = txn.revision
+	receiver.events = append(receiver.events, txn.events...)
+	for watcher := range receiver.watchers {
+		watcher.send(receiver.header(), txn.events)
+	}
+}
+
+// 撤销写事务中的变化（需在加锁后调用）
+func (receiver *memoryStore) rollback(txn *memoryTxn) {
+	for index := len(txn.events) - 1; index >= 0; index-- {
+		event := txn.events[index]
+		key := string(event.Kv.Key)
+		history := receiver.keys[key]
+		if len(history) <= 1 {
+			delete(receiver.keys, key)
+		} else {
+			receiver.keys[key] = history[:len(history)-1]
+		}
+		receiver.detachLease(event.Kv)
+		if event.PrevKv != nil {
+			if lease := receiver.leases[event.PrevKv.Lease]; lease != nil {
+				lease.keys[key] = struct{}{}
+			}
+		}
+	}
+	txn.events = nil
+}
+
+// KEY在指定Revision时的值，0：最新的值（需在加锁后调用）
+func (receiver *memoryStore) get(key string, revision int64) *mvccpb.KeyValue {
+	history := receiver.keys[key]
+	for index := len(history) - 1; index >= 0; index-- {
+		if revision == 0 || history[index].ModRevision <= revision {
+			if history[index].CreateRevision == 0 {
+				return nil
+			}
+			return history[index]
+		}
+	}
+	return nil
+}
+
+// 范围内的KEY在指定Revision时的值，按KEY排序（需在加锁后调用）
+func (receiver *memoryStore) rangeKeys(key, rangeEnd []byte, revision int64) []*mvccpb.KeyValue {
+	var kvs []*mvccpb.KeyValue
+	if len(rangeEnd) == 0 {
+		if kv := receiver.get(string(key), revision); kv != nil {
+			kvs = append(kvs, kv)
+		}
+		return kvs
+	}
+	for k := range receiver.keys {
+		if inRange([]byte(k), key, rangeEnd) {
+			if kv := receiver.get(k, revision); kv != nil {
+				kvs = append(kvs, kv)
+			}
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0 })
+	return kvs
+}
+
+// rangeEnd为空时只匹配key，为\x00时匹配所有大于等于key的KEY
+func inRange(k, key, rangeEnd []byte) bool {
+	switch {
+	case len(rangeEnd) == 0:
+		return bytes.Equal(k, key)
+	case len(rangeEnd) == 1 && rangeEnd[0] == 0:
+		return bytes.Compare(k, key) >= 0
+	default:
+		return bytes.Compare(k, key) >= 0 && bytes.Compare(k, rangeEnd) < 0
+	}
+}
+
+// 需在加锁后调用
+func (receiver *memoryStore) doRange(request *pb.RangeRequest) (*pb.RangeResponse, error) {
+	if request.Revision > receiver.revision {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+	if request.Revision > 0 && request.Revision < receiver.compacted {
+		return nil, rpctypes.ErrGRPCCompacted
+	}
+
+	kvs := receiver.rangeKeys(request.Key, request.RangeEnd, request.Revision)
+	rsp := &pb.RangeResponse{Count: int64(len(kvs))}
+
+	filtered := kvs[:0:0]
+	for _, kv := range kvs {
+		if (request.MinModRevision > 0 && kv.ModRevision < request.MinModRevision) ||
+			(request.MaxModRevision > 0 && kv.ModRevision > request.MaxModRevision) ||
+			(request.MinCreateRevision > 0 && kv.CreateRevision < request.MinCreateRevision) ||
+			(request.MaxCreateRevision > 0 && kv.CreateRevision > request.MaxCreateRevision) {
+			continue
+		}
+		filtered = append(filtered, kv)
+	}
+	sortKeyValues(filtered, request.SortTarget, request.SortOrder)
+
+	if request.CountOnly {
+		return rsp, nil
+	}
+	if request.Limit > 0 && int64(len(filtered)) > request.Limit {
+		filtered, rsp.More = filtered[:request.Limit], true
+	}
+	for _, kv := range filtered {
+		kv = cloneKeyValue(kv)
+		if request.KeysOnly {
+			kv.Value = nil
+		}
+		rsp.Kvs = append(rsp.Kvs, kv)
+	}
+	return rsp, nil
+}
+
+// 按指定的字段排序（kvs已按KEY升序）
+func sortKeyValues(kvs []*mvccpb.KeyValue, target pb.RangeRequest_SortTarget, order pb.RangeRequest_SortOrder) {
+	if order == pb.RangeRequest_NONE {
+		// 与etcd一致：按非KEY字段排序但未指定顺序时，默认升序
+		if target == pb.RangeRequest_KEY {
+			return
+		}
+		order = pb.RangeRequest_ASCEND
+	}
+	less := func(i, j int) bool {
+		switch target {
+		case pb.RangeRequest_VERSION:
+			return kvs[i].Version < kvs[j].Version
+		case pb.RangeRequest_CREATE:
+			return kvs[i].CreateRevision < kvs[j].CreateRevision
+		case pb.RangeRequest_MOD:
+			return kvs[i].ModRevision < kvs[j].ModRevision
+		case pb.RangeRequest_VALUE:
+			return bytes.Compare(kvs[i].Value, kvs[j].Value) < 0
+		default:
+			return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0
+		}
+	}
+	if order == pb.RangeRequest_DESCEND {
+		sort.SliceStable(kvs, func(i, j int) bool { return less(j, i) })
+		return
+	}
+	sort.SliceStable(kvs, less)
+}
+
+func cloneKeyValue(kv *mvccpb.KeyValue) *mvccpb.KeyValue {
+	if kv == nil {
+		return nil
+	}
+	clone := *kv
+	return &clone
+}
+
+// 需在加锁后调用
+func (receiver *memoryStore) doPut(txn *memoryTxn, request *pb.PutRequest) (*pb.PutResponse, error) {
+	key := string(request.Key)
+	prev := receiver.get(key, 0)
+	value, lease := request.Value, request.Lease
+	if request.IgnoreValue || request.IgnoreLease {
+		if prev == nil {
+			return nil, rpctypes.ErrGRPCKeyNotFound
+		}
+		if request.IgnoreValue {
+			value = prev.Value
+		}
+		if request.IgnoreLease {
+			lease = prev.Lease
+		}
+	}
+	if lease != 0 && receiver.leases[lease] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+
+	kv := &mvccpb.KeyValue{Key: request.Key, Value: value, Lease: lease, ModRevision: txn.revision, CreateRevision: txn.revision, Version: 1}
+	if prev != nil {
+		kv.CreateRevision, kv.Version = prev.CreateRevision, prev.Version+1
+		receiver.detachLease(prev)
+	}
+	if lease != 0 {
+		receiver.leases[lease].keys[key] = struct{}{}
+	}
+	receiver.keys[key] = append(receiver.keys[key], kv)
+	txn.events = append(txn.events, &mvccpb.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+
+	rsp := &pb.PutResponse{}
+	if request.PrevKv {
+		rsp.PrevKv = cloneKeyValue(prev)
+	}
+	return rsp, nil
+}
+
+// 需在加锁后调用
+func (receiver *memoryStore) doDeleteRange(txn *memoryTxn, request *pb.DeleteRangeRequest) *pb.DeleteRangeResponse {
+	kvs := receiver.rangeKeys(request.Key, request.RangeEnd, 0)
+	rsp := &pb.DeleteRangeResponse{Deleted: int64(len(kvs))}
+	for _, prev := range kvs {
+		receiver.deleteKey(txn, prev)
+		if request.PrevKv {
+			rsp.PrevKvs = append(rsp.PrevKvs, cloneKeyValue(prev))
+		}
+	}
+	return rsp
+}
+
+// 写入墓碑（需在加锁后调用）
+func (receiver *memoryStore) deleteKey(txn *memoryTxn, prev *mvccpb.KeyValue) {
+	key := string(prev.Key)
+	tombstone := &mvccpb.KeyValue{Key: prev.Key, ModRevision: txn.revision}
+	receiver.keys[key] = append(receiver.keys[key], tombstone)
+	receiver.detachLease(prev)
+	txn.events = append(txn.events, &mvccpb.Event{Type: mvccpb.DELETE, Kv: tombstone, PrevKv: prev})
+}
+
+func (receiver *memoryStore) detachLease(kv *mvccpb.KeyValue) {
+	if lease := receiver.leases[kv.Lease]; lease != nil {
+		delete(lease.keys, string(kv.Key))
+	}
+}
+
+// 需在加锁后调用
+func (receiver *memoryStore) doTxn(txn *memoryTxn, request *pb.TxnRequest) (*pb.TxnResponse, error) {
+	succeeded := true
+	for _, compare := range request.Compare {
+		if !receiver.compare(compare) {
+			succeeded = false
+			break
+		}
+	}
+	ops := request.Success
+	if !succeeded {
+		ops = request.Failure
+	}
+	if err := checkDuplicateKeys(ops); err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.TxnResponse{Succeeded: succeeded, Responses: make([]*pb.ResponseOp, 0, len(ops))}
+	for _, op := range ops {
+		var responseOp *pb.ResponseOp
+		switch request := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			rangeRsp, err := receiver.doRange(request.RequestRange)
+			if err != nil {
+				return nil, err
+			}
+			responseOp = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rangeRsp}}
+		case *pb.RequestOp_RequestPut:
+			putRsp, err := receiver.doPut(txn, request.RequestPut)
+			if err != nil {
+				return nil, err
+			}
+			responseOp = &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: putRsp}}
+		case *pb.RequestOp_RequestDeleteRange:
+			deleteRsp := receiver.doDeleteRange(txn, request.RequestDeleteRange)
+			responseOp = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: deleteRsp}}
+		case *pb.RequestOp_RequestTxn:
+			txnRsp, err := receiver.doTxn(txn, request.RequestTxn)
+			if err != nil {
+				return nil, err
+			}
+			responseOp = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: txnRsp}}
+		}
+		rsp.Responses = append(rsp.Responses, responseOp)
+	}
+	return rsp, nil
+}
+
+// 事务提交后再设置响应头（Revision为提交后的Revision）
+func setTxnHeader(rsp *pb.TxnResponse, header *pb.ResponseHeader) {
+	rsp.Header = header
+	for _, responseOp := range rsp.Responses {
+		switch response := responseOp.Response.(type) {
+		case *pb.ResponseOp_ResponseRange:
+			response.ResponseRange.Header = header
+		case *pb.ResponseOp_ResponsePut:
+			response.ResponsePut.Header = header
+		case *pb.ResponseOp_ResponseDeleteRange:
+			response.ResponseDeleteRange.Header = header
+		case *pb.ResponseOp_ResponseTxn:
+			setTxnHeader(response.ResponseTxn, header)
+		}
+	}
+}
+
+// 与etcd一致：同一个事务中不能重复写入同一个KEY
+func checkDuplicateKeys(ops []*pb.RequestOp) error {
+	keys := make(map[string]struct{})
+	for _, op := range ops {
+		if put := op.GetRequestPut(); put != nil {
+			if _, exists := keys[string(put.Key)]; exists {
+				return rpctypes.ErrGRPCDuplicateKey
+			}
+			keys[string(put.Key)] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// 范围内所有的KEY都满足条件才成立（需在加锁后调用）
+func (receiver *memoryStore) compare(compare *pb.Compare) bool {
+	kvs := receiver.rangeKeys(compare.Key, compare.RangeEnd, 0)
+	if len(kvs) == 0 {
+		// KEY不存在时按零值比较（值的比较不成立）
+		if compare.Target == pb.Compare_VALUE {
+			return false
+		}
+		kvs = append(kvs, &mvccpb.KeyValue{})
+	}
+	for _, kv := range kvs {
+		var result int
+		switch compare.Target {
+		case pb.Compare_VERSION:
+			result = compareInt64(kv.Version, compare.GetVersion())
+		case pb.Compare_CREATE:
+			result = compareInt64(kv.CreateRevision, compare.GetCreateRevision())
+		case pb.Compare_MOD:
+			result = compareInt64(kv.ModRevision, compare.GetModRevision())
+		case pb.Compare_VALUE:
+			result = bytes.Compare(kv.Value, compare.GetValue())
+		case pb.Compare_LEASE:
+			result = compareInt64(kv.Lease, compare.GetLease())
+		}
+		var matched bool
+		switch compare.Result {
+		case pb.Compare_EQUAL:
+			matched = result == 0
+		case pb.Compare_NOT_EQUAL:
+			matched = result != 0
+		case pb.Compare_GREATER:
+			matched = result > 0
+		case pb.Compare_LESS:
+			matched = result < 0
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// 压缩revision之前的历史版本（需在加锁后调用）
+func (receiver *memoryStore) compact(revision int64) error {
+	if revision <= receiver.compacted {
+		return rpctypes.ErrGRPCCompacted
+	}
+	if revision > receiver.revision {
+		return rpctypes.ErrGRPCFutureRev
+	}
+	for key, history := range receiver.keys {
+		// 保留revision时的版本，以及之后的版本
+		index := 0
+		for index+1 < len(history) && history[index+1].ModRevision <= revision {
+			index++
+		}
+		if history[index].ModRevision <= revision && history[index].CreateRevision == 0 {
+			index++
+		}
+		if index >= len(history) {
+			delete(receiver.keys, key)
+			continue
+		}
+		receiver.keys[key] = history[index:]
+	}
+
+	// 删除已压缩的事件（保留revision时的事件，用于从压缩的Revision开始Watch）
+	index := sort.Search(len(receiver.events), func(i int) bool { return receiver.events[i].Kv.ModRevision >= revision })
+	receiver.events = append([]*mvccpb.Event(nil), receiver.events[index:]...)
+	receiver.compacted = revision
+	return nil
+}
+
+// 计算revision时所有KV的hash（需在加锁后调用）
+func (receiver *memoryStore) hash(revision int64) uint32 {
+	hash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	for _, kv := range receiver.rangeKeys([]byte{0}, []byte{0}, revision) {
+		_, _ = hash.Write(kv.Key)
+		_, _ = hash.Write(kv.Value)
+	}
+	return hash.Sum32()
+}
+
+// 所有KV的大小（模拟DB大小，需在加锁后调用）
+func (receiver *memoryStore) size() int64 {
+	var size int64
+	for _, history := range receiver.keys {
+		for _, kv := range history {
+			size += int64(kv.Size())
+		}
+	}
+	return size
+}
+
+// 需在加锁后调用
+func (receiver *memoryStore) grant(id int64, ttl int64) (*memoryLease, error) {
+	if id == 0 {
+		for receiver.leaseId++; receiver.leases[receiver.leaseId] != nil; receiver.leaseId++ {
+		}
+		id = receiver.leaseId
+	}
+	if receiver.leases[id] != nil {
+		return nil, rpctypes.ErrGRPCLeaseExist
+	}
+	lease := &memoryLease{id: id, ttl: ttl, expireAt: receiver.now.Add(time.Duration(ttl) * time.Second), keys: make(map[string]struct{})}
+	receiver.leases[id] = lease
+	return lease, nil
+}
+
+// 删除租约及其关联的KEY（需在加锁后调用）
+func (receiver *memoryStore) revoke(id int64) error {
+	lease := receiver.leases[id]
+	if lease == nil {
+		return rpctypes.ErrGRPCLeaseNotFound
+	}
+	txn := receiver.begin()
+	keys := make([]string, 0, len(lease.keys))
+	for key := range lease.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if kv := receiver.get(key, 0); kv != nil {
+			receiver.deleteKey(txn, kv)
+		}
+	}
+	delete(receiver.leases, id)
+	receiver.commit(txn)
+	return nil
+}
+
+// 租约剩余的时间（单位s，向上取整）
+func (receiver *memoryStore) remaining(lease *memoryLease) int64 {
+	remaining := lease.expireAt.Sub(receiver.now)
+	return int64((remaining + time.Second - 1) / time.Second)
+}
+
+// 时钟前进d，删除已过期的租约，并发送Watch的进度通知
+func (receiver *memoryStore) advance(d time.Duration) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.now = receiver.now.Add(d)
+	var expired []int64
+	for id, lease := range receiver.leases {
+		if !lease.expireAt.After(receiver.now) {
+			expired = append(expired, id)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i] < expired[j] })
+	for _, id := range expired {
+		_ = receiver.revoke(id)
+	}
+	for watcher := range receiver.watchers {
+		watcher.progress(receiver.header(), receiver.now)
+	}
+}
@@ -0,0 +1,231 @@
+package etcd
+
+import (
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// 开启ProgressNotify时，发送进度通知的间隔（与etcd的默认值一致，使用模拟的时钟）
+const memoryProgressInterval = 10 * time.Minute
+
+// 内存集群的Watch服务
+type memoryWatchServer struct {
+	store *memoryStore
+}
+
+// 一个Watch连接（一个连接上可以有多个Watch）
+type memoryWatchStream struct {
+	lock    sync.Mutex
+	queue   []*pb.WatchResponse // 待发送的响应（写入KEY时不能阻塞，所以不限制长度）
+	notify  chan struct{}
+	closed  bool
+	watchId int64 // 最后分配的WatchId
+}
+
+// 一个Watch
+type memoryWatcher struct {
+	id             int64
+	key            []byte
+	rangeEnd       []byte
+	startRevision  int64 // 开始的Revision（大于当前Revision时，之前的变化不发送）
+	prevKv         bool
+	filterPut      bool
+	filterDelete   bool
+	progressNotify bool      // 一个间隔内没有发送变化时，发送进度通知
+	progressAt     time.Time // 下一次检查是否需要发送进度通知的时间（模拟的时钟）
+	eventsSent     bool      // 当前间隔内是否发送过变化
+	stream         *memoryWatchStream
+}
+
+func (receiver *memoryWatchServer) Watch(server pb.Watch_WatchServer) error {
+	stream := &memoryWatchStream{notify: make(chan struct{}, 1), watchId: -1}
+	watchers := make(map[int64]*memoryWatcher)
+	defer func() {
+		receiver.store.lock.Lock()
+		for _, watcher := range watchers {
+			delete(receiver.store.watchers, watcher)
+		}
+		receiver.store.lock.Unlock()
+	}()
+
+	// 由一个协程按顺序发送
+	sendDone := make(chan struct{})
+	go func() {
+		defer close(sendDone)
+		for {
+			rsp, ok := stream.pop(server.Context().Done())
+			if !ok || server.Send(rsp) != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		stream.close()
+		<-sendDone
+	}()
+
+	for {
+		request, err := server.Recv()
+		if err != nil {
+			return nil
+		}
+		switch request := request.RequestUnion.(type) {
+		case *pb.WatchRequest_CreateRequest:
+			watcher := receiver.create(stream, request.CreateRequest)
+			watchers[watcher.id] = watcher
+		case *pb.WatchRequest_CancelRequest:
+			receiver.store.lock.Lock()
+			if watcher, exists := watchers[request.CancelRequest.WatchId]; exists {
+				delete(receiver.store.watchers, watcher)
+				delete(watchers, watcher.id)
+			}
+			stream.push(&pb.WatchResponse{Header: receiver.store.header(), WatchId: request.CancelRequest.WatchId, Canceled: true})
+			receiver.store.lock.Unlock()
+		case *pb.WatchRequest_ProgressRequest:
+			// 所有的Watch都已同步，与etcd一致，WatchId为-1
+			receiver.store.lock.Lock()
+			stream.push(&pb.WatchResponse{Header: receiver.store.header(), WatchId: -1})
+			receiver.store.lock.Unlock()
+		}
+	}
+}
+
+// 创建Watch：先发送历史的变化，再接收新的变化（在同一把锁内，不会遗漏）
+func (receiver *memoryWatchServer) create(stream *memoryWatchStream, request *pb.WatchCreateRequest) *memoryWatcher {
+	store := receiver.store
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	stream.watchId++
+	watcher := &memoryWatcher{
+		id:             stream.watchId,
+		key:            request.Key,
+		rangeEnd:       request.RangeEnd,
+		startRevision:  request.StartRevision,
+		prevKv:         request.PrevKv,
+		progressNotify: request.ProgressNotify,
+		progressAt:     store.now.Add(memoryProgressInterval),
+		stream:         stream,
+	}
+	for _, filter := range request.Filters {
+		switch filter {
+		case pb.WatchCreateRequest_NOPUT:
+			watcher.filterPut = true
+		case pb.WatchCreateRequest_NODELETE:
+			watcher.filterDelete = true
+		}
+	}
+	stream.push(&pb.WatchResponse{Header: store.header(), WatchId: watcher.id, Created: true})
+
+	// 开始的Revision已被压缩（与etcd一致，可以从压缩时的Revision开始）
+	if request.StartRevision > 0 && request.StartRevision < store.compacted {
+		stream.push(&pb.WatchResponse{Header: store.header(), WatchId: watcher.id, CompactRevision: store.compacted, Canceled: true})
+		return watcher
+	}
+
+	// 历史的变化（按Revision分批发送）
+	if request.StartRevision > 0 {
+		var events []*mvccpb.Event
+		for _, event := range store.events {
+			if event.Kv.ModRevision < request.StartRevision {
+				continue
+			}
+			if len(events) > 0 && events[0].Kv.ModRevision != event.Kv.ModRevision {
+				watcher.send(store.header(), events)
+				events = nil
+			}
+			events = append(events, event)
+		}
+		if len(events) > 0 {
+			watcher.send(store.header(), events)
+		}
+	}
+	store.watchers[watcher] = struct{}{}
+	return watcher
+}
+
+// 发送匹配的变化（需在加锁后调用）
+func (receiver *memoryWatcher) send(header *pb.ResponseHeader, events []*mvccpb.Event) {
+	var matched []*mvccpb.Event
+	for _, event := range events {
+		if event.Kv.ModRevision < receiver.startRevision ||
+			!inRange(event.Kv.Key, receiver.key, receiver.rangeEnd) ||
+			(event.Type == mvccpb.PUT && receiver.filterPut) ||
+			(event.Type == mvccpb.DELETE && receiver.filterDelete) {
+			continue
+		}
+		event = &mvccpb.Event{Type: event.Type, Kv: cloneKeyValue(event.Kv), PrevKv: cloneKeyValue(event.PrevKv)}
+		if !receiver.prevKv {
+			event.PrevKv = nil
+		}
+		matched = append(matched, event)
+	}
+	if len(matched) > 0 {
+		receiver.eventsSent = true
+		receiver.stream.push(&pb.WatchResponse{Header: header, WatchId: receiver.id, Events: matched})
+	}
+}
+
+// 到达间隔时，如果间隔内没有发送过变化，发送进度通知（与etcd一致，需在加锁后调用）
+func (receiver *memoryWatcher) progress(header *pb.ResponseHeader, now time.Time) {
+	if !receiver.progressNotify || now.Before(receiver.progressAt) {
+		return
+	}
+	if !receiver.eventsSent {
+		receiver.stream.push(&pb.WatchResponse{Header: header, WatchId: receiver.id})
+	}
+	receiver.eventsSent = false
+	receiver.progressAt = now.Add(memoryProgressInterval)
+}
+
+func (receiver *memoryWatchStream) push(rsp *pb.WatchResponse) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.closed {
+		return
+	}
+	receiver.queue = append(receiver.queue, rsp)
+	select {
+	case receiver.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 取出下一个响应，连接关闭时返回false
+func (receiver *memoryWatchStream) pop(done <-chan struct{}) (*pb.WatchResponse, bool) {
+	for {
+		receiver.lock.Lock()
+		if receiver.closed {
+			receiver.lock.Unlock()
+			return nil, false
+		}
+		if len(receiver.queue) > 0 {
+			rsp := receiver.queue[0]
+			receiver.queue[0] = nil
+			receiver.queue = receiver.queue[1:]
+			receiver.lock.Unlock()
+			return rsp, true
+		}
+		receiver.lock.Unlock()
+
+		select {
+		case <-receiver.notify:
+		case <-done:
+			return nil, false
+		}
+	}
+}
+
+func (receiver *memoryWatchStream) close() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	receiver.closed = true
+	receiver.queue = nil
+	select {
+	case receiver.notify <- struct{}{}:
+	default:
+	}
+}
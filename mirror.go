@@ -0,0 +1,165 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/trace"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// Mirror 将KEY前缀下的所有KV缓存到本地，并通过Watch保持同步
+// 读取时无锁（每次变化时复制一份新的快照）
+type Mirror struct {
+	etcdCli      *etcdClient
+	traceManager trace.IManager
+	prefix       string
+	snapshot     atomic.Value // *mirrorSnapshot
+	ready        chan struct{}
+	readyOnce    sync.Once
+	cancel       context.CancelFunc
+}
+
+// 本地缓存的快照
+type mirrorSnapshot struct {
+	kvs      map[string]*KeyValue
+	revision int64
+}
+
+// NewMirror 将KEY前缀下的所有KV缓存到本地，并通过Watch保持同步
+func NewMirror(client IClient, prefix string) *Mirror {
+	cli := clientOf(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	mirror := &Mirror{
+		etcdCli:      cli.etcdCli,
+		traceManager: cli.traceManager,
+		prefix:       prefix,
+		ready:        make(chan struct{}),
+		cancel:       cancel,
+	}
+	mirror.snapshot.Store(&mirrorSnapshot{kvs: make(map[string]*KeyValue)})
+	go mirror.sync(ctx)
+	return mirror
+}
+
+// Get 从本地缓存获取KV
+func (receiver *Mirror) Get(key string) (*KeyValue, bool) {
+	kv, exists := receiver.load().kvs[key]
+	return kv, exists
+}
+
+// List 本地缓存的所有KV（返回的map不能修改）
+func (receiver *Mirror) List() map[string]*KeyValue {
+	return receiver.load().kvs
+}
+
+// Len 本地缓存的KV数量
+func (receiver *Mirror) Len() int {
+	return len(receiver.load().kvs)
+}
+
+// Revision 本地缓存所对应的集群Revision
+func (receiver *Mirror) Revision() int64 {
+	return receiver.load().revision
+}
+
+// IsReady 是否已完成首次加载
+func (receiver *Mirror) IsReady() bool {
+	select {
+	case <-receiver.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitReady 等待首次加载完成
+func (receiver *Mirror) WaitReady(ctx context.Context) error {
+	select {
+	case <-receiver.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 停止同步
+func (receiver *Mirror) Close() {
+	receiver.cancel()
+}
+
+func (receiver *Mirror) load() *mirrorSnapshot {
+	return receiver.snapshot.Load().(*mirrorSnapshot)
+}
+
+// 全量加载后，从加载时的Revision开始监听变化；监听中断（如Revision已被压缩）时，重新全量加载
+func (receiver *Mirror) sync(ctx context.Context) {
+	for ctx.Err() == nil {
+		revision, err := receiver.resync(ctx)
+		if err == nil {
+			err = receiver.watch(ctx, revision)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		flog.Warningf("Etcd镜像：%s 同步中断，重新全量加载：%s", receiver.prefix, err.Error())
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// 全量加载
+func (receiver *Mirror) resync(ctx context.Context) (int64, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("MirrorResync", receiver.prefix, 0)
+	rsp, err := receiver.etcdCli.Get(ctx, receiver.prefix, etcdV3.WithPrefix())
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return 0, err
+	}
+	kvs := make(map[string]*KeyValue, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		kvs[string(kv.Key)] = newValue(kv, rsp.Header)
+	}
+	receiver.snapshot.Store(&mirrorSnapshot{kvs: kvs, revision: rsp.Header.Revision})
+	receiver.readyOnce.Do(func() { close(receiver.ready) })
+	return rsp.Header.Revision, nil
+}
+
+// 监听变化，并应用到本地缓存
+func (receiver *Mirror) watch(ctx context.Context, revision int64) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for watchRsp := range receiver.etcdCli.Watch(watchCtx, receiver.prefix, etcdV3.WithPrefix(), etcdV3.WithRev(revision+1), etcdV3.WithProgressNotify()) {
+		if err := watchRsp.Err(); err != nil {
+			return err
+		}
+
+		old := receiver.load()
+		snapshot := &mirrorSnapshot{kvs: old.kvs, revision: watchRsp.Header.Revision}
+		if len(watchRsp.Events) > 0 {
+			snapshot.kvs = make(map[string]*KeyValue, len(old.kvs)+len(watchRsp.Events))
+			for key, kv := range old.kvs {
+				snapshot.kvs[key] = kv
+			}
+			for _, event := range watchRsp.Events {
+				if event.Type == mvccpb.DELETE {
+					delete(snapshot.kvs, string(event.Kv.Key))
+				} else {
+					snapshot.kvs[string(event.Kv.Key)] = newValue(event.Kv, &watchRsp.Header)
+				}
+			}
+		}
+		receiver.snapshot.Store(snapshot)
+	}
+	return fmt.Errorf("监听已关闭")
+}
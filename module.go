@@ -1,6 +1,9 @@
 package etcd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/farseer-go/fs/configure"
 	"github.com/farseer-go/fs/container"
 	"github.com/farseer-go/fs/core"
@@ -23,16 +26,91 @@ func (module Module) PreInitialize() {
 func (module Module) Initialize() {
 	etcdConfigs := configure.GetSubNodes("Etcd")
 	for name, configString := range etcdConfigs {
-		config := configure.ParseString[etcdConfig](configString.(string))
-		if config.Server == "" {
-			_ = flog.Error("Etcd配置缺少Server节点")
-			continue
+		if err := Register(name, configString.(string)); err != nil {
+			_ = flog.Error(err)
 		}
+	}
+}
+
+// Register 以name注册客户端到容器（配置的格式与配置文件中的Etcd节点相同）
+// 已注册时替换并立即关闭原连接：已从容器中取出的客户端（包括自动压缩、定时备份）之后的请求都会失败，需要重新取出
+func Register(name string, configString string) error {
+	config := configure.ParseString[etcdConfig](configString)
+	if config.Server == "" {
+		return fmt.Errorf("Etcd配置缺少Server节点")
+	}
+
+	// 注册实例（同一个配置共用一个连接）
+	pool := newClientPool(name, func() (IClient, error) { return openClient(config) })
+	registerPool(name, pool)
+
+	// 开启自动压缩（多个实例之间选举，只有Leader执行）
+	if config.AutoCompactionMode != "" {
+		startAutoCompaction(name, pool, config)
+	}
+
+	// 开启定时备份
+	if config.BackupDir != "" {
+		startBackupSchedule(name, pool, config)
+	}
+	return nil
+}
+
+// 以name注册到容器（替换已有的注册），每次取出时获取连接池的引用
+func registerPool(name string, pool *clientPool) {
+	if container.IsRegister[IClient](name) {
+		container.Remove[IClient](name)
+	}
+	container.RegisterTransient(func() IClient {
+		client, err := pool.acquire()
+		flog.ErrorIfExists(err)
+		return client
+	}, name)
+}
+
+// Unregister 从容器中移除name的客户端，并立即关闭连接（已取出的客户端之后的请求都会失败）
+func Unregister(name string) {
+	if container.IsRegister[IClient](name) {
+		container.Remove[IClient](name)
+	}
+	removeClientPool(name)
+}
+
+func (module Module) Shutdown() {
+	// 关闭所有的客户端
+	closeAll()
+}
 
-		// 注册实例
-		container.RegisterTransient(func() IClient {
-			client, _ := open(config)
-			return client
-		}, name)
+func startAutoCompaction(name string, pool *clientPool, config etcdConfig) {
+	client, err := pool.acquire()
+	if err != nil {
+		_ = flog.Errorf("Etcd.%s 开启自动压缩失败：%s", name, err.Error())
+		return
+	}
+	_, err = NewCompactor(client, CompactionConfig{
+		Mode:         config.AutoCompactionMode,
+		Retention:    config.AutoCompactionRetention,
+		DefragWindow: config.DefragWindow,
+	})
+	if err != nil {
+		_ = flog.Errorf("Etcd.%s 开启自动压缩失败：%s", name, err.Error())
+		client.Close()
+	}
+}
+
+func startBackupSchedule(name string, pool *clientPool, config etcdConfig) {
+	interval, err := time.ParseDuration(config.BackupInterval)
+	if err != nil || interval <= 0 {
+		_ = flog.Errorf("Etcd.%s 定时备份的间隔不正确：%s", name, config.BackupInterval)
+		return
+	}
+	client, err := pool.acquire()
+	if err != nil {
+		_ = flog.Errorf("Etcd.%s 开启定时备份失败：%s", name, err.Error())
+		return
+	}
+	if _, err = NewBackupSchedule(client, config.BackupDir, interval, config.BackupRetain); err != nil {
+		client.Close()
+		_ = flog.Errorf("Etcd.%s 开启定时备份失败：%s", name, err.Error())
 	}
 }
@@ -0,0 +1,56 @@
+package etcd
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+	"google.golang.org/grpc"
+)
+
+// 创建带KEY前缀（命名空间）的客户端，与conn共用同一个连接
+// 所有的KV、Watch、租约、锁操作都会自动加上前缀，返回的KEY会自动去掉前缀
+// memoryConn：内存客户端的连接（conn没有grpc连接，需通过它创建Watcher、Lease），其它客户端为nil
+func newNamespaceClient(conn *etcdClient, memoryConn *grpc.ClientConn, prefix string) *etcdClient {
+	cli := etcdV3.NewCtxClient(conn.Ctx())
+	cli.KV = namespace.NewKV(conn.KV, prefix)
+	// Watcher、Lease在Close时会被关闭，所以单独创建，不影响conn
+	if memoryConn != nil {
+		cli.Watcher = namespace.NewWatcher(etcdV3.NewWatchFromWatchClient(pb.NewWatchClient(memoryConn), conn), prefix)
+		cli.Lease = namespace.NewLease(etcdV3.NewLeaseFromLeaseClient(pb.NewLeaseClient(memoryConn), conn, time.Second), prefix)
+	} else {
+		cli.Watcher = namespace.NewWatcher(etcdV3.NewWatcher(conn), prefix)
+		cli.Lease = namespace.NewLease(etcdV3.NewLease(conn), prefix)
+	}
+	cli.Cluster = conn.Cluster
+	cli.Auth = conn.Auth
+	cli.Maintenance = conn.Maintenance
+	return cli
+}
+
+func (receiver *client) WithNamespace(prefix string) IClient {
+	conn := receiver.connection()
+	return &client{
+		etcdCli:      newNamespaceClient(conn, receiver.memoryConn, receiver.namespace+prefix),
+		conn:         conn,
+		namespace:    receiver.namespace + prefix,
+		isView:       true,
+		traceManager: receiver.traceManager,
+		monitor:      receiver.monitor,
+		memoryConn:   receiver.memoryConn,
+	}
+}
+
+// Namespace 当前客户端的KEY前缀
+func (receiver *client) Namespace() string {
+	return receiver.namespace
+}
+
+// 原始的连接（不带KEY前缀）
+func (receiver *client) connection() *etcdClient {
+	if receiver.conn != nil {
+		return receiver.conn
+	}
+	return receiver.etcdCli
+}
@@ -0,0 +1,445 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"github.com/farseer-go/fs/snc"
+	"github.com/farseer-go/fs/sonyflake"
+	"github.com/farseer-go/fs/trace"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrQueueItemExpired 消息的可见性超时已过期（已被重新投递），不能再Ack/Nack
+var ErrQueueItemExpired = fmt.Errorf("消息已过期")
+
+// Queue 分布式队列（优先级高的先出队，同优先级按入队顺序）
+// KEY的结构：
+// name/items/id：消息内容（持久化）
+// name/ready/优先级/id：待消费的消息
+// name/inflight/id：消费中的消息（带租约，租约到期后消息重新投递）
+// name/dead/id：超过最大投递次数的消息（死信）
+type Queue struct {
+	etcdCli       *etcdClient
+	traceManager  trace.IManager
+	prefix        string // 队列KEY前缀
+	visibilityTTL int64  // 可见性超时（单位s），出队后在这个时间内没有Ack，消息将重新投递
+	maxAttempts   int    // 最大投递次数，超过后移到死信，0：不限制
+	cancel        context.CancelFunc
+}
+
+// QueueItem 队列消息
+type QueueItem struct {
+	Id        int64   // 消息ID
+	Value     string  // 消息内容
+	Priority  uint16  // 优先级（越大越先出队）
+	Attempts  int     // 已投递次数
+	EnqueueAt int64   // 入队时间（unix毫秒）
+	lease     LeaseID // 出队时的租约
+}
+
+// QueueStats 队列统计
+type QueueStats struct {
+	Ready    int64 // 待消费的消息数量
+	Inflight int64 // 消费中的消息数量
+	Dead     int64 // 死信数量
+}
+
+// NewQueue 分布式队列，name：队列KEY前缀，visibilityTTL：可见性超时（单位s），maxAttempts：最大投递次数（0：不限制）
+// 后台监听消费中消息的租约到期，将消息重新投递，不再使用时需调用Close
+func NewQueue(client IClient, name string, visibilityTTL int64, maxAttempts int) *Queue {
+	cli := clientOf(client)
+	ctx, cancel := context.WithCancel(cli.connection().Ctx())
+	queue := &Queue{
+		etcdCli:       cli.etcdCli,
+		traceManager:  cli.traceManager,
+		prefix:        strings.TrimSuffix(name, "/") + "/",
+		visibilityTTL: visibilityTTL,
+		maxAttempts:   maxAttempts,
+		cancel:        cancel,
+	}
+	go queue.recoverLoop(ctx)
+	return queue
+}
+
+// Close 停止监听租约到期（不影响队列中的消息）
+func (receiver *Queue) Close() {
+	receiver.cancel()
+}
+
+// Enqueue 入队，priority：优先级（越大越先出队，默认0）
+func (receiver *Queue) Enqueue(value string, priority ...uint16) (*QueueItem, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("QueueEnqueue", receiver.prefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	item := &QueueItem{
+		Id:        sonyflake.GenerateId(),
+		Value:     value,
+		EnqueueAt: time.Now().UnixMilli(),
+	}
+	if len(priority) > 0 {
+		item.Priority = priority[0]
+	}
+
+	jsonValue, _ := snc.Marshal(item)
+	_, err = receiver.etcdCli.Txn(todo).Then(
+		etcdV3.OpPut(receiver.itemKey(item.Id), string(jsonValue)),
+		etcdV3.OpPut(receiver.readyKey(item), ""),
+	).Commit()
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Dequeue 出队，队列为空时阻塞等待，直到有消息或ctx取消
+// 出队后需在visibilityTTL内调用Ack，否则消息将重新投递
+func (receiver *Queue) Dequeue(ctx context.Context) (*QueueItem, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("QueueDequeue", receiver.prefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	for {
+		var item *QueueItem
+		var revision int64
+		if item, revision, err = receiver.tryDequeue(ctx); err != nil || item != nil {
+			return item, err
+		}
+
+		// 队列为空，等待队列有变化后再重试
+		if err = receiver.waitChange(ctx, revision+1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// TryDequeue 出队，队列为空时立即返回nil
+func (receiver *Queue) TryDequeue() (*QueueItem, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("QueueTryDequeue", receiver.prefix, 0)
+	item, _, err := receiver.tryDequeue(todo)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return item, err
+}
+
+// Ack 确认消息已消费完成，将消息从队列中删除
+func (receiver *Queue) Ack(item *QueueItem) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("QueueAck", receiver.itemKey(item.Id), int64(item.lease))
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	inflightKey := receiver.inflightKey(item.Id)
+	txnRsp, err := receiver.etcdCli.Txn(todo).
+		If(etcdV3.Compare(etcdV3.LeaseValue(inflightKey), "=", int64(item.lease))).
+		Then(etcdV3.OpDelete(receiver.itemKey(item.Id)), etcdV3.OpDelete(inflightKey)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnRsp.Succeeded {
+		err = ErrQueueItemExpired
+		return err
+	}
+	_, _ = receiver.etcdCli.Revoke(todo, etcdV3.LeaseID(item.lease))
+	return nil
+}
+
+// Nack 消费失败，消息立即重新投递（超过最大投递次数时，移到死信）
+func (receiver *Queue) Nack(item *QueueItem) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("QueueNack", receiver.itemKey(item.Id), int64(item.lease))
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	inflightKey := receiver.inflightKey(item.Id)
+	ops := append([]etcdV3.Op{etcdV3.OpDelete(inflightKey)}, receiver.requeueOps(item)...)
+	txnRsp, err := receiver.etcdCli.Txn(todo).
+		If(etcdV3.Compare(etcdV3.LeaseValue(inflightKey), "=", int64(item.lease))).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnRsp.Succeeded {
+		err = ErrQueueItemExpired
+		return err
+	}
+	_, _ = receiver.etcdCli.Revoke(todo, etcdV3.LeaseID(item.lease))
+	return nil
+}
+
+// Len 待消费的消息数量
+func (receiver *Queue) Len() (int64, error) {
+	return receiver.count(receiver.prefix + "ready/")
+}
+
+// Stats 队列统计
+func (receiver *Queue) Stats() (QueueStats, error) {
+	var stats QueueStats
+	var err error
+	if stats.Ready, err = receiver.count(receiver.prefix + "ready/"); err != nil {
+		return stats, err
+	}
+	if stats.Inflight, err = receiver.count(receiver.prefix + "inflight/"); err != nil {
+		return stats, err
+	}
+	stats.Dead, err = receiver.count(receiver.prefix + "dead/")
+	return stats, err
+}
+
+// Items 队列中的所有消息（包括待消费、消费中）
+func (receiver *Queue) Items() ([]*QueueItem, error) {
+	return receiver.list("QueueItems", receiver.prefix+"items/")
+}
+
+// DeadLetters 死信列表
+func (receiver *Queue) DeadLetters() ([]*QueueItem, error) {
+	return receiver.list("QueueDeadLetters", receiver.prefix+"dead/")
+}
+
+// tryDequeue 取出优先级最高、最早入队的消息，队列为空时返回nil及当前集群Revision
+func (receiver *Queue) tryDequeue(ctx context.Context) (*QueueItem, int64, error) {
+	for {
+		// ready KEY按优先级、消息ID（入队时间）排序，第一个即为优先级最高、最早入队的消息
+		readyRsp, err := receiver.etcdCli.Get(ctx, receiver.prefix+"ready/", etcdV3.WithPrefix(), etcdV3.WithSort(etcdV3.SortByKey, etcdV3.SortAscend), etcdV3.WithLimit(1))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(readyRsp.Kvs) == 0 {
+			return nil, readyRsp.Header.Revision, nil
+		}
+		readyKV := readyRsp.Kvs[0]
+
+		item, itemModRevision, err := receiver.getItem(ctx, readyKV.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if item == nil {
+			continue
+		}
+		if item, err = receiver.claim(ctx, item, itemModRevision, string(readyKV.Key), readyKV.ModRevision); err != nil || item != nil {
+			return item, 0, err
+		}
+		// 被其它客户端抢先出队，重试
+	}
+}
+
+// 读取ready KEY对应的消息内容
+func (receiver *Queue) getItem(ctx context.Context, readyKey []byte) (*QueueItem, int64, error) {
+	rsp, err := receiver.etcdCli.Get(ctx, receiver.itemKey(queueItemId(readyKey)))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(rsp.Kvs) == 0 {
+		// 消息内容已不存在，清理残留的ready KEY
+		_, err = receiver.etcdCli.Delete(ctx, string(readyKey))
+		return nil, 0, err
+	}
+	var item QueueItem
+	if err = snc.Unmarshal(rsp.Kvs[0].Value, &item); err != nil {
+		return nil, 0, err
+	}
+	return &item, rsp.Kvs[0].ModRevision, nil
+}
+
+// 抢占消息：删除ready KEY，写入带租约的inflight KEY，投递次数+1
+func (receiver *Queue) claim(ctx context.Context, item *QueueItem, itemModRevision int64, readyKey string, readyModRevision int64) (*QueueItem, error) {
+	leaseRsp, err := receiver.etcdCli.Grant(ctx, receiver.visibilityTTL)
+	if err != nil {
+		return nil, err
+	}
+	item.Attempts++
+	item.lease = LeaseID(leaseRsp.ID)
+
+	jsonValue, _ := snc.Marshal(item)
+	itemKey := receiver.itemKey(item.Id)
+	txnRsp, err := receiver.etcdCli.Txn(ctx).
+		If(etcdV3.Compare(etcdV3.ModRevision(readyKey), "=", readyModRevision), etcdV3.Compare(etcdV3.ModRevision(itemKey), "=", itemModRevision)).
+		Then(
+			etcdV3.OpDelete(readyKey),
+			etcdV3.OpPut(itemKey, string(jsonValue)),
+			etcdV3.OpPut(receiver.inflightKey(item.Id), "", etcdV3.WithLease(leaseRsp.ID)),
+		).Commit()
+	if err != nil || !txnRsp.Succeeded {
+		_, _ = receiver.etcdCli.Revoke(context.Background(), leaseRsp.ID)
+		return nil, err
+	}
+	return item, nil
+}
+
+// 监听inflight KEY的删除（租约到期），将过期的消息重新投递
+// 开始监听前（以及监听中断后）先全量检查一次，处理没有客户端监听期间过期的消息
+func (receiver *Queue) recoverLoop(ctx context.Context) {
+	for ctx.Err() == nil {
+		revision, err := receiver.recover(ctx)
+		if err == nil {
+			err = receiver.watchExpired(ctx, revision+1)
+		}
+		if err != nil && ctx.Err() == nil {
+			flog.Warningf("Etcd队列：%s 重新投递过期消息失败：%s", receiver.prefix, err.Error())
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// 从revision开始监听inflight KEY的删除，逐个检查被删除的消息（Ack、Nack删除的不会重新投递）
+func (receiver *Queue) watchExpired(ctx context.Context, revision int64) error {
+	inflightPrefix := receiver.prefix + "inflight/"
+	for watchRsp := range receiver.etcdCli.Watch(ctx, inflightPrefix, etcdV3.WithPrefix(), etcdV3.WithRev(revision), etcdV3.WithFilterPut()) {
+		if err := watchRsp.Err(); err != nil {
+			return err
+		}
+		for _, event := range watchRsp.Events {
+			if err := receiver.recoverItem(ctx, queueItemId(event.Kv.Key)); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("队列监听已关闭：%s", receiver.prefix)
+}
+
+// 重新投递一个租约已过期的消息
+func (receiver *Queue) recoverItem(ctx context.Context, id int64) error {
+	rsp, err := receiver.etcdCli.Get(ctx, receiver.itemKey(id))
+	if err != nil || len(rsp.Kvs) == 0 {
+		return err
+	}
+	return receiver.requeueExpired(ctx, rsp.Kvs[0])
+}
+
+// recover 将租约已过期（既不在ready，也不在inflight）的消息重新投递，返回检查时的集群Revision
+func (receiver *Queue) recover(ctx context.Context) (int64, error) {
+	itemsRsp, err := receiver.etcdCli.Get(ctx, receiver.prefix+"items/", etcdV3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	if len(itemsRsp.Kvs) == 0 {
+		return itemsRsp.Header.Revision, nil
+	}
+	readyRsp, err := receiver.etcdCli.Get(ctx, receiver.prefix+"ready/", etcdV3.WithPrefix(), etcdV3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+	inflightRsp, err := receiver.etcdCli.Get(ctx, receiver.prefix+"inflight/", etcdV3.WithPrefix(), etcdV3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+
+	// 在ready或inflight中的消息ID
+	activeIds := make(map[int64]struct{}, len(readyRsp.Kvs)+len(inflightRsp.Kvs))
+	for _, kv := range append(readyRsp.Kvs, inflightRsp.Kvs...) {
+		activeIds[queueItemId(kv.Key)] = struct{}{}
+	}
+
+	for _, kv := range itemsRsp.Kvs {
+		if _, isActive := activeIds[queueItemId(kv.Key)]; isActive {
+			continue
+		}
+		if err = receiver.requeueExpired(ctx, kv); err != nil {
+			return 0, err
+		}
+	}
+	return itemsRsp.Header.Revision, nil
+}
+
+// 消息既不在ready，也不在inflight时重新投递（消息在查询之后被其它客户端修改时，事务不会执行）
+func (receiver *Queue) requeueExpired(ctx context.Context, kv *mvccpb.KeyValue) error {
+	var item QueueItem
+	if snc.Unmarshal(kv.Value, &item) != nil {
+		return nil
+	}
+	_, err := receiver.etcdCli.Txn(ctx).
+		If(
+			etcdV3.Compare(etcdV3.ModRevision(string(kv.Key)), "=", kv.ModRevision),
+			etcdV3.Compare(etcdV3.CreateRevision(receiver.inflightKey(item.Id)), "=", 0),
+			etcdV3.Compare(etcdV3.CreateRevision(receiver.readyKey(&item)), "=", 0),
+		).
+		Then(receiver.requeueOps(&item)...).
+		Commit()
+	return err
+}
+
+// 重新投递消息，超过最大投递次数时移到死信
+func (receiver *Queue) requeueOps(item *QueueItem) []etcdV3.Op {
+	if receiver.maxAttempts > 0 && item.Attempts >= receiver.maxAttempts {
+		jsonValue, _ := snc.Marshal(item)
+		return []etcdV3.Op{
+			etcdV3.OpDelete(receiver.itemKey(item.Id)),
+			etcdV3.OpPut(receiver.prefix+fmt.Sprintf("dead/%d", item.Id), string(jsonValue)),
+		}
+	}
+	return []etcdV3.Op{etcdV3.OpPut(receiver.readyKey(item), "")}
+}
+
+// 等待队列有变化（入队、消息租约到期等）
+func (receiver *Queue) waitChange(ctx context.Context, revision int64) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watchRsp, ok := <-receiver.etcdCli.Watch(watchCtx, receiver.prefix, etcdV3.WithPrefix(), etcdV3.WithRev(revision))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !ok {
+		return fmt.Errorf("队列监听已关闭：%s", receiver.prefix)
+	}
+	return watchRsp.Err()
+}
+
+func (receiver *Queue) count(prefix string) (int64, error) {
+	rsp, err := receiver.etcdCli.Get(todo, prefix, etcdV3.WithPrefix(), etcdV3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return rsp.Count, nil
+}
+
+func (receiver *Queue) list(method string, prefix string) ([]*QueueItem, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd(method, prefix, 0)
+	rsp, err := receiver.etcdCli.Get(todo, prefix, etcdV3.WithPrefix(), etcdV3.WithSort(etcdV3.SortByCreateRevision, etcdV3.SortAscend))
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*QueueItem, 0, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		var item QueueItem
+		if snc.Unmarshal(kv.Value, &item) == nil {
+			items = append(items, &item)
+		}
+	}
+	return items, nil
+}
+
+func (receiver *Queue) itemKey(id int64) string {
+	return fmt.Sprintf("%sitems/%d", receiver.prefix, id)
+}
+
+func (receiver *Queue) inflightKey(id int64) string {
+	return fmt.Sprintf("%sinflight/%d", receiver.prefix, id)
+}
+
+// 优先级越大，KEY越小（按KEY排序时排在前面），同优先级按消息ID（补齐位数后按KEY排序即为入队顺序，Nack后不会排到后面）
+func (receiver *Queue) readyKey(item *QueueItem) string {
+	return fmt.Sprintf("%sready/%05d/%019d", receiver.prefix, math.MaxUint16-int(item.Priority), item.Id)
+}
+
+// 从items、ready、inflight的KEY中取出消息ID（KEY的最后一段）
+func queueItemId(key []byte) int64 {
+	id, _ := strconv.ParseInt(string(key[bytes.LastIndexByte(key, '/')+1:]), 10, 64)
+	return id
+}
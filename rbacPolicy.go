@@ -0,0 +1,202 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+)
+
+// 内置的root用户、角色（Prune时不会删除）
+const rootName = "root"
+
+// RBACPolicy 期望的用户、角色、权限
+type RBACPolicy struct {
+	Roles      []Role       // 角色及其权限（会撤销未声明的权限）
+	Users      []UserPolicy // 用户及其角色（会撤销未声明的角色）
+	Prune      bool         // 删除未声明的用户、角色（root除外）
+	EnableAuth bool         // 调整完成后开启认证（需声明root用户或已存在）
+	DryRun     bool         // 只返回将要执行的变更，不实际执行
+}
+
+// UserPolicy 期望的用户
+type UserPolicy struct {
+	Name     string   // 用户名
+	Password string   // 密码（仅在创建用户时使用）
+	Roles    []string // 拥有的角色
+}
+
+// 执行变更（DryRun时只记录）
+type rbacReconciler struct {
+	auth    *auth
+	ctx     context.Context
+	dryRun  bool
+	changes []string
+}
+
+func (receiver *rbacReconciler) apply(change string, fn func() error) error {
+	receiver.changes = append(receiver.changes, change)
+	if receiver.dryRun {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return fmt.Errorf("%s失败：%s", change, err.Error())
+	}
+	return nil
+}
+
+func (receiver *auth) ApplyPolicy(ctx context.Context, policy RBACPolicy) ([]string, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("ApplyPolicy", "", 0)
+	reconciler := &rbacReconciler{auth: receiver, ctx: ctx, dryRun: policy.DryRun}
+	err := reconciler.reconcile(policy)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return reconciler.changes, err
+}
+
+func (receiver *rbacReconciler) reconcile(policy RBACPolicy) error {
+	existsRoles, err := receiver.auth.RoleList(receiver.ctx)
+	if err != nil {
+		return err
+	}
+	existsUsers, err := receiver.auth.UserList(receiver.ctx)
+	if err != nil {
+		return err
+	}
+	roleSet, userSet := toSet(existsRoles), toSet(existsUsers)
+
+	// 1、角色及权限
+	policyRoles := make(map[string]bool)
+	for _, role := range policy.Roles {
+		policyRoles[role.Name] = true
+		if err = receiver.reconcileRole(role, roleSet[role.Name]); err != nil {
+			return err
+		}
+	}
+
+	// 2、用户及角色
+	policyUsers := make(map[string]bool)
+	for _, user := range policy.Users {
+		policyUsers[user.Name] = true
+		if err = receiver.reconcileUser(user, userSet[user.Name]); err != nil {
+			return err
+		}
+	}
+
+	// 3、删除未声明的用户、角色
+	if policy.Prune {
+		for _, name := range existsUsers {
+			if !policyUsers[name] && name != rootName {
+				if err = receiver.apply("删除用户："+name, func() error { return receiver.auth.UserDelete(receiver.ctx, name) }); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range existsRoles {
+			if !policyRoles[name] && name != rootName {
+				if err = receiver.apply("删除角色："+name, func() error { return receiver.auth.RoleDelete(receiver.ctx, name) }); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// 4、开启认证
+	if policy.EnableAuth {
+		enabled, err := receiver.auth.AuthStatus(receiver.ctx)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return receiver.apply("开启认证", func() error { return receiver.auth.AuthEnable(receiver.ctx) })
+		}
+	}
+	return nil
+}
+
+func (receiver *rbacReconciler) reconcileRole(role Role, exists bool) error {
+	current := make(map[string]Permission)
+	if exists {
+		existsRole, err := receiver.auth.RoleGet(receiver.ctx, role.Name)
+		if err != nil {
+			return err
+		}
+		for _, permission := range existsRole.Permissions {
+			permission = permission.normalize()
+			current[permission.Key+"\x00"+permission.rangeEnd()] = permission
+		}
+	} else if err := receiver.apply("添加角色："+role.Name, func() error { return receiver.auth.RoleAdd(receiver.ctx, role.Name) }); err != nil {
+		return err
+	}
+
+	// 授予缺少（或类型不一致）的权限，同一个范围重复授予时会覆盖权限类型
+	desired := make(map[string]bool)
+	for _, permission := range role.Permissions {
+		permission = permission.normalize()
+		rangeKey := permission.Key + "\x00" + permission.rangeEnd()
+		desired[rangeKey] = true
+		if currentPermission, isExists := current[rangeKey]; isExists && currentPermission.Type == permission.Type {
+			continue
+		}
+		if err := receiver.apply(fmt.Sprintf("角色：%s 授予权限：%s", role.Name, permission.String()), func() error {
+			return receiver.auth.RoleGrantPermission(receiver.ctx, role.Name, permission)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// 撤销未声明的权限
+	for rangeKey, permission := range current {
+		if desired[rangeKey] {
+			continue
+		}
+		if err := receiver.apply(fmt.Sprintf("角色：%s 撤销权限：%s", role.Name, permission.String()), func() error {
+			return receiver.auth.RoleRevokePermission(receiver.ctx, role.Name, permission)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (receiver *rbacReconciler) reconcileUser(user UserPolicy, exists bool) error {
+	current := make(map[string]bool)
+	if exists {
+		existsUser, err := receiver.auth.UserGet(receiver.ctx, user.Name)
+		if err != nil {
+			return err
+		}
+		current = toSet(existsUser.Roles)
+	} else if err := receiver.apply("添加用户："+user.Name, func() error { return receiver.auth.UserAdd(receiver.ctx, user.Name, user.Password) }); err != nil {
+		return err
+	}
+
+	desired := toSet(user.Roles)
+	for _, role := range user.Roles {
+		if current[role] {
+			continue
+		}
+		if err := receiver.apply(fmt.Sprintf("用户：%s 授予角色：%s", user.Name, role), func() error {
+			return receiver.auth.UserGrantRole(receiver.ctx, user.Name, role)
+		}); err != nil {
+			return err
+		}
+	}
+	for role := range current {
+		if desired[role] {
+			continue
+		}
+		if err := receiver.apply(fmt.Sprintf("用户：%s 撤销角色：%s", user.Name, role), func() error {
+			return receiver.auth.UserRevokeRole(receiver.ctx, user.Name, role)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,339 @@
+package etcd
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farseer-go/fs/snc"
+)
+
+const (
+	RecordOk       = "ok"        // 成功
+	RecordNotFound = "not_found" // KEY不存在（Get、Exists）
+	RecordError    = "error"     // 失败
+)
+
+// RecordEntry 录制的一次调用（文件中每行一个JSON）
+type RecordEntry struct {
+	Seq       int64    `json:"seq"`                 // 调用的顺序（按开始调用的先后，文件中按调用结束的先后写入）
+	Op        string   `json:"op"`                  // 调用的方法
+	Key       string   `json:"key,omitempty"`       // KEY（含命名空间）
+	Keys      []string `json:"keys,omitempty"`      // LeaseGrant：赋加租约的KEY（含命名空间）
+	ValueSize int      `json:"valueSize,omitempty"` // 写入的值的大小（不记录值的内容）
+	Value     string   `json:"value,omitempty"`     // 写入的值为整数时记录值（回放后Incr、Decr才能成功）
+	Delta     int64    `json:"delta,omitempty"`     // Incr、Decr：增减的值
+	TTL       int64    `json:"ttl,omitempty"`       // LeaseGrant、Lock：租约时间（单位s）
+	LeaseId   int64    `json:"leaseId,omitempty"`   // 租约ID（LeaseGrant时为创建的租约ID）
+	Count     int      `json:"count,omitempty"`     // GetPrefixKey：KEY的数量
+	Result    string   `json:"result"`              // ok、not_found、error
+	Error     string   `json:"error,omitempty"`     // 失败时的错误
+	Revision  int64    `json:"revision,omitempty"`  // 响应的集群Revision
+	Start     int64    `json:"start"`               // 开始录制后多久调用（单位μs）
+	Duration  int64    `json:"duration"`            // 耗时（单位μs）
+}
+
+// Recorder 录制客户端的调用到文件（用于复现线上问题、用真实的负载压测），通过Wrap包装客户端
+// 录制IClient的KV、Watch、租约、锁，通过NewSemaphore(client, ...)等函数创建的对象直接使用底层的连接，不录制
+type Recorder struct {
+	lock    sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	startAt time.Time
+	seq     int64
+	count   int
+	err     error // 第一次写入失败的错误
+	closed  bool
+}
+
+// 包装后的客户端
+type recordClient struct {
+	inner    IClient
+	recorder *Recorder
+}
+
+// NewRecorder 创建录制，path：录制的文件（已存在时覆盖）
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, writer: bufio.NewWriter(file), startAt: time.Now()}, nil
+}
+
+// Wrap 包装客户端，通过包装后的客户端调用时录制
+func (receiver *Recorder) Wrap(client IClient) IClient {
+	return &recordClient{inner: client, recorder: receiver}
+}
+
+// Count 已录制的调用次数
+func (receiver *Recorder) Count() int {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	return receiver.count
+}
+
+// Close 停止录制，并关闭文件（之后的调用不再录制）
+func (receiver *Recorder) Close() error {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.closed {
+		return receiver.err
+	}
+	receiver.closed = true
+	if err := receiver.writer.Flush(); err != nil && receiver.err == nil {
+		receiver.err = err
+	}
+	if err := receiver.file.Close(); err != nil && receiver.err == nil {
+		receiver.err = err
+	}
+	return receiver.err
+}
+
+// 一次调用的开始
+type recordCall struct {
+	seq     int64
+	startAt time.Time
+}
+
+// 开始一次调用（分配顺序号）
+func (receiver *Recorder) begin() recordCall {
+	return recordCall{seq: atomic.AddInt64(&receiver.seq, 1), startAt: time.Now()}
+}
+
+// 写入一次调用
+func (receiver *Recorder) write(entry RecordEntry, call recordCall, err error) {
+	entry.Seq = call.seq
+	entry.Duration = time.Since(call.startAt).Microseconds()
+	entry.Start = call.startAt.Sub(receiver.startAt).Microseconds()
+	if err != nil {
+		entry.Result, entry.Error = RecordError, err.Error()
+	} else if entry.Result == "" {
+		entry.Result = RecordOk
+	}
+
+	jsonValue, marshalErr := snc.Marshal(entry)
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.closed || receiver.err != nil {
+		return
+	}
+	if marshalErr != nil {
+		receiver.err = marshalErr
+		return
+	}
+	_, _ = receiver.writer.Write(jsonValue)
+	if writeErr := receiver.writer.WriteByte('\n'); writeErr != nil {
+		receiver.err = writeErr
+		return
+	}
+	receiver.count++
+}
+
+// 加上命名空间的KEY
+func (receiver *recordClient) key(key string) string {
+	return receiver.inner.Namespace() + key
+}
+
+// 记录写入的值的大小，整数同时记录值
+func recordValue(entry RecordEntry, value string) RecordEntry {
+	entry.ValueSize = len(value)
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		entry.Value = value
+	}
+	return entry
+}
+
+func headerRevision(header *Header) int64 {
+	if header == nil {
+		return 0
+	}
+	return header.Revision
+}
+
+// 被包装的客户端
+func (receiver *recordClient) unwrap() IClient {
+	return receiver.inner
+}
+
+func (receiver *recordClient) Close() {
+	receiver.inner.Close()
+}
+
+func (receiver *recordClient) Put(key, value string) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.Put(key, value)
+	receiver.recorder.write(recordValue(RecordEntry{Op: "Put", Key: receiver.key(key), Revision: headerRevision(header)}, value), call, err)
+	return header, err
+}
+
+func (receiver *recordClient) PutLease(key, value string, leaseId LeaseID) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.PutLease(key, value, leaseId)
+	receiver.recorder.write(recordValue(RecordEntry{Op: "PutLease", Key: receiver.key(key), LeaseId: int64(leaseId), Revision: headerRevision(header)}, value), call, err)
+	return header, err
+}
+
+func (receiver *recordClient) PutJson(key string, data any) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.PutJson(key, data)
+	jsonValue, _ := snc.Marshal(data)
+	receiver.recorder.write(recordValue(RecordEntry{Op: "PutJson", Key: receiver.key(key), Revision: headerRevision(header)}, string(jsonValue)), call, err)
+	return header, err
+}
+
+func (receiver *recordClient) PutJsonLease(key string, data any, leaseId LeaseID) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.PutJsonLease(key, data, leaseId)
+	jsonValue, _ := snc.Marshal(data)
+	receiver.recorder.write(recordValue(RecordEntry{Op: "PutJsonLease", Key: receiver.key(key), LeaseId: int64(leaseId), Revision: headerRevision(header)}, string(jsonValue)), call, err)
+	return header, err
+}
+
+func (receiver *recordClient) Get(key string) (*KeyValue, error) {
+	call := receiver.recorder.begin()
+	result, err := receiver.inner.Get(key)
+	entry := RecordEntry{Op: "Get", Key: receiver.key(key)}
+	if err == nil {
+		entry.Revision, entry.ValueSize = headerRevision(result.Header), len(result.Value)
+		if !result.Exists() {
+			entry.Result = RecordNotFound
+		}
+	}
+	receiver.recorder.write(entry, call, err)
+	return result, err
+}
+
+func (receiver *recordClient) GetPrefixKey(prefixKey string) (map[string]*KeyValue, error) {
+	call := receiver.recorder.begin()
+	result, err := receiver.inner.GetPrefixKey(prefixKey)
+	entry := RecordEntry{Op: "GetPrefixKey", Key: receiver.key(prefixKey), Count: len(result)}
+	for _, kv := range result {
+		entry.ValueSize += len(kv.Value)
+		entry.Revision = headerRevision(kv.Header)
+	}
+	receiver.recorder.write(entry, call, err)
+	return result, err
+}
+
+func (receiver *recordClient) Delete(key string) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.Delete(key)
+	receiver.recorder.write(RecordEntry{Op: "Delete", Key: receiver.key(key), Revision: headerRevision(header)}, call, err)
+	return header, err
+}
+
+func (receiver *recordClient) DeletePrefixKey(prefixKey string) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.DeletePrefixKey(prefixKey)
+	receiver.recorder.write(RecordEntry{Op: "DeletePrefixKey", Key: receiver.key(prefixKey), Revision: headerRevision(header)}, call, err)
+	return header, err
+}
+
+func (receiver *recordClient) Exists(key string) bool {
+	call := receiver.recorder.begin()
+	exists := receiver.inner.Exists(key)
+	entry := RecordEntry{Op: "Exists", Key: receiver.key(key)}
+	if !exists {
+		entry.Result = RecordNotFound
+	}
+	receiver.recorder.write(entry, call, nil)
+	return exists
+}
+
+func (receiver *recordClient) Incr(key string, delta int64) (int64, error) {
+	call := receiver.recorder.begin()
+	result, err := receiver.inner.Incr(key, delta)
+	receiver.recorder.write(RecordEntry{Op: "Incr", Key: receiver.key(key), Delta: delta}, call, err)
+	return result, err
+}
+
+func (receiver *recordClient) Decr(key string, delta int64) (int64, error) {
+	call := receiver.recorder.begin()
+	result, err := receiver.inner.Decr(key, delta)
+	receiver.recorder.write(RecordEntry{Op: "Decr", Key: receiver.key(key), Delta: delta}, call, err)
+	return result, err
+}
+
+func (receiver *recordClient) Watch(ctx context.Context, key string, watchFunc func(event WatchEvent)) {
+	call := receiver.recorder.begin()
+	receiver.inner.Watch(ctx, key, watchFunc)
+	receiver.recorder.write(RecordEntry{Op: "Watch", Key: receiver.key(key)}, call, nil)
+}
+
+func (receiver *recordClient) WatchPrefixKey(ctx context.Context, prefixKey string, watchFunc func(event WatchEvent)) {
+	call := receiver.recorder.begin()
+	receiver.inner.WatchPrefixKey(ctx, prefixKey, watchFunc)
+	receiver.recorder.write(RecordEntry{Op: "WatchPrefixKey", Key: receiver.key(prefixKey)}, call, nil)
+}
+
+func (receiver *recordClient) LeaseGrant(ttl int64, keys ...string) (LeaseID, error) {
+	call := receiver.recorder.begin()
+	leaseId, err := receiver.inner.LeaseGrant(ttl, keys...)
+	entry := RecordEntry{Op: "LeaseGrant", TTL: ttl, LeaseId: int64(leaseId)}
+	for _, key := range keys {
+		entry.Keys = append(entry.Keys, receiver.key(key))
+	}
+	receiver.recorder.write(entry, call, err)
+	return leaseId, err
+}
+
+func (receiver *recordClient) LeaseKeepAlive(ctx context.Context, leaseId LeaseID) error {
+	call := receiver.recorder.begin()
+	err := receiver.inner.LeaseKeepAlive(ctx, leaseId)
+	receiver.recorder.write(RecordEntry{Op: "LeaseKeepAlive", LeaseId: int64(leaseId)}, call, err)
+	return err
+}
+
+func (receiver *recordClient) LeaseKeepAliveOnce(leaseId LeaseID) error {
+	call := receiver.recorder.begin()
+	err := receiver.inner.LeaseKeepAliveOnce(leaseId)
+	receiver.recorder.write(RecordEntry{Op: "LeaseKeepAliveOnce", LeaseId: int64(leaseId)}, call, err)
+	return err
+}
+
+func (receiver *recordClient) LeaseRevoke(leaseId LeaseID) (*Header, error) {
+	call := receiver.recorder.begin()
+	header, err := receiver.inner.LeaseRevoke(leaseId)
+	receiver.recorder.write(RecordEntry{Op: "LeaseRevoke", LeaseId: int64(leaseId), Revision: headerRevision(header)}, call, err)
+	return header, err
+}
+
+func (receiver *recordClient) LeaseInfo(leaseId LeaseID) (*LeaseInfo, error) {
+	call := receiver.recorder.begin()
+	info, err := receiver.inner.LeaseInfo(leaseId)
+	receiver.recorder.write(RecordEntry{Op: "LeaseInfo", LeaseId: int64(leaseId)}, call, err)
+	return info, err
+}
+
+// Lock 解锁时同时录制UnLock（先录制再解锁，使UnLock在等待这个锁的Lock结束之前开始，回放时据此并发执行）
+func (receiver *recordClient) Lock(lockKey string, lockTTL int) (UnLock, error) {
+	call := receiver.recorder.begin()
+	unLock, err := receiver.inner.Lock(lockKey, lockTTL)
+	receiver.recorder.write(RecordEntry{Op: "Lock", Key: receiver.key(lockKey), TTL: int64(lockTTL)}, call, err)
+	if err != nil {
+		return unLock, err
+	}
+	return func() {
+		receiver.recorder.write(RecordEntry{Op: "UnLock", Key: receiver.key(lockKey)}, receiver.recorder.begin(), nil)
+		unLock()
+	}, nil
+}
+
+// WithNamespace 命名空间客户端录制到同一个文件（KEY含命名空间）
+func (receiver *recordClient) WithNamespace(prefix string) IClient {
+	return &recordClient{inner: receiver.inner.WithNamespace(prefix), recorder: receiver.recorder}
+}
+
+func (receiver *recordClient) Namespace() string {
+	return receiver.inner.Namespace()
+}
+
+func (receiver *recordClient) Original() *etcdClient {
+	return receiver.inner.Original()
+}
@@ -0,0 +1,385 @@
+package etcd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/snc"
+)
+
+const (
+	defaultReplayConcurrency = 64               // 默认同时进行的调用数量上限
+	defaultReplayLockTimeout = 10 * time.Second // 默认Lock最多等待的时间
+)
+
+// 回放的Lock等待超时
+var errReplayLockTimeout = fmt.Errorf("回放Lock等待超时")
+
+// ReplayOptions 回放的选项
+type ReplayOptions struct {
+	Speed       float64       // 按录制时的时间间隔回放的倍速（1：原速，2：两倍速），<=0时不等待，尽快回放（用于压测）
+	Concurrency int           // 同时进行的调用数量上限，默认64
+	LockTimeout time.Duration // Lock最多等待的时间，超时视为失败（避免锁的先后顺序与录制时不一致时一直阻塞），默认10s
+}
+
+// ReplayOpStats 每种操作的回放统计
+type ReplayOpStats struct {
+	Count       int           // 回放的次数
+	Errors      int           // 失败的次数
+	Mismatch    int           // 结果（ok、not_found、error）与录制时不一致的次数
+	Elapsed     time.Duration // 总耗时
+	MaxElapsed  time.Duration // 最大耗时
+	RecordedAvg time.Duration // 录制时的平均耗时
+	recordedSum time.Duration
+}
+
+// ReplayResult 回放的结果
+type ReplayResult struct {
+	Total    int                       // 回放的调用次数
+	Skipped  int                       // 跳过的调用次数（不支持的操作、录制前创建的租约）
+	Errors   int                       // 失败的次数
+	Mismatch int                       // 结果与录制时不一致的次数
+	Elapsed  time.Duration             // 回放的总耗时
+	Ops      map[string]*ReplayOpStats // 每种操作的统计
+}
+
+// 回放的状态
+type replayer struct {
+	client      IClient
+	ctx         context.Context
+	lockTimeout time.Duration
+	lock        sync.Mutex
+	leases      map[int64]LeaseID // 录制时的租约ID -> 回放时创建的租约ID
+	unLocks     map[string][]UnLock
+	values      map[int]string // 按大小缓存生成的值
+	result      *ReplayResult
+}
+
+// 回放中的调用
+type replayCall struct {
+	end  int64 // 录制时的结束时间（单位μs）
+	done chan struct{}
+}
+
+// ReplayFile 回放录制的文件，见Replay
+func ReplayFile(ctx context.Context, client IClient, path string, options ReplayOptions) (*ReplayResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return Replay(ctx, client, file, options)
+}
+
+// Replay 回放录制的调用（可以是内存客户端，也可以是真实的客户端），写入的值使用与录制时相同大小的生成值（整数使用录制的值）
+// 按录制时开始调用的顺序调度：录制时在某个调用结束后才开始的调用，回放时也等它结束后才开始，录制时并发的调用回放时也并发
+// 录制的KEY含命名空间，需使用不带命名空间的客户端回放；回放结束后，取消Watch、续租，并释放未解锁的锁
+func Replay(ctx context.Context, client IClient, reader io.Reader, options ReplayOptions) (*ReplayResult, error) {
+	result := &ReplayResult{Ops: make(map[string]*ReplayOpStats)}
+	entries, err := readRecord(reader)
+	if err != nil {
+		return result, err
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultReplayConcurrency
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = defaultReplayLockTimeout
+	}
+
+	replayCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	state := &replayer{
+		client:      client,
+		ctx:         replayCtx,
+		lockTimeout: options.LockTimeout,
+		leases:      make(map[int64]LeaseID),
+		unLocks:     make(map[string][]UnLock),
+		values:      make(map[int]string),
+		result:      result,
+	}
+	defer state.release()
+
+	startAt := time.Now()
+	slots := make(chan struct{}, options.Concurrency)
+	var wg sync.WaitGroup
+	var running []replayCall
+	for _, entry := range entries {
+		// 等待录制时在这个调用开始前已结束的调用
+		if running, err = waitCalls(ctx, running, entry.Start); err != nil {
+			break
+		}
+
+		// 按录制时的时间间隔等待
+		if options.Speed > 0 {
+			if wait := time.Until(startAt.Add(time.Duration(float64(entry.Start)/options.Speed) * time.Microsecond)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+				}
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		call := replayCall{end: entry.Start + entry.Duration, done: make(chan struct{})}
+		running = append(running, call)
+		wg.Add(1)
+		go func(entry RecordEntry) {
+			defer func() {
+				close(call.done)
+				<-slots
+				wg.Done()
+			}()
+			opAt := time.Now()
+			replayResult, replayed := state.replay(entry)
+			state.stat(entry, replayResult, replayed, time.Since(opAt))
+		}(entry)
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(startAt)
+	return result, err
+}
+
+// 读取录制的调用，按开始调用的顺序排序
+func readRecord(reader io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry RecordEntry
+		if err := snc.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("第%d行JSON解析失败：%s", line, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Seq != entries[j].Seq {
+			return entries[i].Seq < entries[j].Seq
+		}
+		return entries[i].Start < entries[j].Start
+	})
+	return entries, scanner.Err()
+}
+
+// 等待录制时在start之前已结束的调用，返回仍需关注的调用
+func waitCalls(ctx context.Context, running []replayCall, start int64) ([]replayCall, error) {
+	pending := running[:0]
+	for _, call := range running {
+		if call.end <= start {
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		select {
+		case <-call.done:
+		default:
+			pending = append(pending, call)
+		}
+	}
+	return pending, nil
+}
+
+// 统计一次调用
+func (receiver *replayer) stat(entry RecordEntry, replayResult string, replayed bool, elapsed time.Duration) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	result := receiver.result
+	if !replayed {
+		result.Skipped++
+		return
+	}
+	stats := result.Ops[entry.Op]
+	if stats == nil {
+		stats = &ReplayOpStats{}
+		result.Ops[entry.Op] = stats
+	}
+	result.Total++
+	stats.Count++
+	stats.Elapsed += elapsed
+	stats.recordedSum += time.Duration(entry.Duration) * time.Microsecond
+	stats.RecordedAvg = stats.recordedSum / time.Duration(stats.Count)
+	if elapsed > stats.MaxElapsed {
+		stats.MaxElapsed = elapsed
+	}
+	if replayResult == RecordError {
+		result.Errors++
+		stats.Errors++
+	}
+	if replayResult != entry.Result {
+		result.Mismatch++
+		stats.Mismatch++
+	}
+}
+
+// 回放一次调用，返回结果（ok、not_found、error），不支持的操作返回false
+func (receiver *replayer) replay(entry RecordEntry) (string, bool) {
+	var err error
+	switch entry.Op {
+	case "Put", "PutJson":
+		_, err = receiver.client.Put(entry.Key, receiver.value(entry))
+	case "PutLease", "PutJsonLease":
+		leaseId, exists := receiver.lease(entry.LeaseId)
+		if !exists {
+			return "", false
+		}
+		_, err = receiver.client.PutLease(entry.Key, receiver.value(entry), leaseId)
+	case "Get":
+		var kv *KeyValue
+		if kv, err = receiver.client.Get(entry.Key); err == nil && !kv.Exists() {
+			return RecordNotFound, true
+		}
+	case "GetPrefixKey":
+		_, err = receiver.client.GetPrefixKey(entry.Key)
+	case "Delete":
+		_, err = receiver.client.Delete(entry.Key)
+	case "DeletePrefixKey":
+		_, err = receiver.client.DeletePrefixKey(entry.Key)
+	case "Exists":
+		if !receiver.client.Exists(entry.Key) {
+			return RecordNotFound, true
+		}
+	case "Incr":
+		_, err = receiver.client.Incr(entry.Key, entry.Delta)
+	case "Decr":
+		_, err = receiver.client.Decr(entry.Key, entry.Delta)
+	case "Watch":
+		receiver.client.Watch(receiver.ctx, entry.Key, func(event WatchEvent) {})
+	case "WatchPrefixKey":
+		receiver.client.WatchPrefixKey(receiver.ctx, entry.Key, func(event WatchEvent) {})
+	case "LeaseGrant":
+		var leaseId LeaseID
+		if leaseId, err = receiver.client.LeaseGrant(entry.TTL, entry.Keys...); err == nil {
+			receiver.lock.Lock()
+			receiver.leases[entry.LeaseId] = leaseId
+			receiver.lock.Unlock()
+		}
+	case "LeaseKeepAlive", "LeaseKeepAliveOnce", "LeaseRevoke", "LeaseInfo":
+		leaseId, exists := receiver.lease(entry.LeaseId)
+		if !exists {
+			return "", false
+		}
+		switch entry.Op {
+		case "LeaseKeepAlive":
+			err = receiver.client.LeaseKeepAlive(receiver.ctx, leaseId)
+		case "LeaseKeepAliveOnce":
+			err = receiver.client.LeaseKeepAliveOnce(leaseId)
+		case "LeaseRevoke":
+			_, err = receiver.client.LeaseRevoke(leaseId)
+		default:
+			_, err = receiver.client.LeaseInfo(leaseId)
+		}
+	case "Lock":
+		err = receiver.acquireLock(entry)
+	case "UnLock":
+		receiver.lock.Lock()
+		unLocks := receiver.unLocks[entry.Key]
+		if len(unLocks) > 0 {
+			receiver.unLocks[entry.Key] = unLocks[1:]
+		}
+		receiver.lock.Unlock()
+		if len(unLocks) == 0 {
+			return "", false
+		}
+		unLocks[0]()
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		return RecordError, true
+	}
+	return RecordOk, true
+}
+
+// 录制时的租约ID对应的回放时的租约ID
+func (receiver *replayer) lease(recordedId int64) (LeaseID, bool) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	leaseId, exists := receiver.leases[recordedId]
+	return leaseId, exists
+}
+
+// 获取锁，最多等待lockTimeout，超时后获取到的锁立即释放
+func (receiver *replayer) acquireLock(entry RecordEntry) error {
+	type lockResult struct {
+		unLock UnLock
+		err    error
+	}
+	resultChan := make(chan lockResult, 1)
+	go func() {
+		unLock, err := receiver.client.Lock(entry.Key, int(entry.TTL))
+		resultChan <- lockResult{unLock: unLock, err: err}
+	}()
+
+	timer := time.NewTimer(receiver.lockTimeout)
+	defer timer.Stop()
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			if result.unLock != nil {
+				result.unLock()
+			}
+			return result.err
+		}
+		receiver.lock.Lock()
+		receiver.unLocks[entry.Key] = append(receiver.unLocks[entry.Key], result.unLock)
+		receiver.lock.Unlock()
+		return nil
+	case <-timer.C:
+	case <-receiver.ctx.Done():
+	}
+	go func() {
+		if result := <-resultChan; result.unLock != nil {
+			result.unLock()
+		}
+	}()
+	return errReplayLockTimeout
+}
+
+// 写入的值：录制的整数值，或指定大小的生成值
+func (receiver *replayer) value(entry RecordEntry) string {
+	if entry.Value != "" {
+		return entry.Value
+	}
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	value, exists := receiver.values[entry.ValueSize]
+	if !exists {
+		value = strings.Repeat("x", entry.ValueSize)
+		receiver.values[entry.ValueSize] = value
+	}
+	return value
+}
+
+// 释放未解锁的锁
+func (receiver *replayer) release() {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	for _, unLocks := range receiver.unLocks {
+		for _, unLock := range unLocks {
+			unLock()
+		}
+	}
+	receiver.unLocks = make(map[string][]UnLock)
+}
@@ -0,0 +1,353 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/flog"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// 刷新源的最新Revision（用于计算延迟）的间隔
+const replicationStatsInterval = 5 * time.Second
+
+// ReplicationConfig 跨集群复制的配置
+type ReplicationConfig struct {
+	Prefixes      []string          // 要复制的KEY前缀
+	Rewrites      []ReplicationRule // KEY改写规则（按顺序匹配第一个），不匹配时KEY不变
+	CheckpointKey string            // 在目标保存每个前缀已复制到的源Revision，重启后从这里继续（为空时每次启动都全量复制）
+	BatchSize     int               // 每个事务包含的KEY数量，默认100，最大128
+}
+
+// ReplicationRule KEY改写规则：将From前缀替换为To
+type ReplicationRule struct {
+	From string
+	To   string
+}
+
+// ReplicationStats 复制的统计
+type ReplicationStats struct {
+	Revision       int64         // 已复制到的源Revision（所有前缀中最小的）
+	SourceRevision int64         // 源的最新Revision
+	LagRevisions   int64         // 落后的Revision数量
+	Lag            time.Duration // 落后的时间（距离上一次追上源的时间），0：已追上
+	Events         int64         // 已复制的事件数量
+	FullSyncs      int64         // 全量复制的次数
+	LastError      string        // 最后一次错误
+}
+
+// Replicator 将源的KEY前缀单向复制到目标（全量复制后，从快照的Revision开始通过Watch持续复制）
+type Replicator struct {
+	src       *etcdClient
+	dst       *etcdClient
+	config    ReplicationConfig
+	lock      sync.RWMutex
+	revisions map[string]int64 // 每个前缀已复制到的源Revision
+	resync    map[string]bool  // 需要重新全量复制的前缀（源已压缩，检查点失效）
+	stats     ReplicationStats
+	caughtUp  time.Time // 最后一次追上源的时间
+	cancel    context.CancelFunc
+}
+
+// Replicate 开始将src的config.Prefixes复制到dst
+func Replicate(src IClient, dst IClient, config ReplicationConfig) (*Replicator, error) {
+	if len(config.Prefixes) == 0 {
+		return nil, fmt.Errorf("复制的KEY前缀不能为空")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultImportBatchSize
+	} else if config.BatchSize > maxImportBatchSize {
+		config.BatchSize = maxImportBatchSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	replicator := &Replicator{
+		src:       clientOf(src).etcdCli,
+		dst:       clientOf(dst).etcdCli,
+		config:    config,
+		revisions: make(map[string]int64),
+		resync:    make(map[string]bool),
+		caughtUp:  time.Now(),
+		cancel:    cancel,
+	}
+	go replicator.run(ctx)
+	go replicator.refreshStats(ctx)
+	return replicator, nil
+}
+
+// Stats 复制的统计
+func (receiver *Replicator) Stats() ReplicationStats {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+
+	stats := receiver.stats
+	stats.Revision = receiver.minRevision()
+	if stats.SourceRevision > stats.Revision {
+		stats.LagRevisions = stats.SourceRevision - stats.Revision
+		stats.Lag = time.Since(receiver.caughtUp)
+	}
+	return stats
+}
+
+// Close 停止复制
+func (receiver *Replicator) Close() {
+	receiver.cancel()
+}
+
+// 需在加锁后调用
+func (receiver *Replicator) minRevision() int64 {
+	var revision int64
+	for index, prefix := range receiver.config.Prefixes {
+		if index == 0 || receiver.revisions[prefix] < revision {
+			revision = receiver.revisions[prefix]
+		}
+	}
+	return revision
+}
+
+func (receiver *Replicator) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := receiver.replicate(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		receiver.lock.Lock()
+		receiver.stats.LastError = err.Error()
+		// 源的Revision已被压缩，只能重新全量复制（忽略目标中的检查点）
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			receiver.revisions = make(map[string]int64)
+			for _, prefix := range receiver.config.Prefixes {
+				receiver.resync[prefix] = true
+			}
+		}
+		receiver.lock.Unlock()
+		flog.Warningf("Etcd跨集群复制中断，1秒后重试：%s", err.Error())
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// 加载检查点（没有时全量复制），然后监听每个前缀的变化
+func (receiver *Replicator) replicate(ctx context.Context) error {
+	if err := receiver.loadCheckpoint(ctx); err != nil {
+		return err
+	}
+
+	for _, prefix := range receiver.config.Prefixes {
+		if receiver.revision(prefix) == 0 || receiver.needFullSync(prefix) {
+			if err := receiver.fullSync(ctx, prefix); err != nil {
+				return err
+			}
+		}
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errChan := make(chan error, len(receiver.config.Prefixes))
+	for _, prefix := range receiver.config.Prefixes {
+		go func(prefix string) { errChan <- receiver.watch(watchCtx, prefix) }(prefix)
+	}
+	// 任意一个前缀中断时，全部重新开始
+	return <-errChan
+}
+
+func (receiver *Replicator) revision(prefix string) int64 {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.revisions[prefix]
+}
+
+func (receiver *Replicator) needFullSync(prefix string) bool {
+	receiver.lock.RLock()
+	defer receiver.lock.RUnlock()
+	return receiver.resync[prefix]
+}
+
+func (receiver *Replicator) setRevision(prefix string, revision int64, events int) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.revisions[prefix] = revision
+	receiver.stats.Events += int64(events)
+	if receiver.minRevision() >= receiver.stats.SourceRevision {
+		receiver.caughtUp = time.Now()
+	}
+}
+
+// 从目标读取检查点（内存中已有进度、或需要重新全量复制时不读取）
+func (receiver *Replicator) loadCheckpoint(ctx context.Context) error {
+	if receiver.config.CheckpointKey == "" {
+		return nil
+	}
+	for _, prefix := range receiver.config.Prefixes {
+		if receiver.revision(prefix) > 0 || receiver.needFullSync(prefix) {
+			continue
+		}
+		rsp, err := receiver.dst.Get(ctx, receiver.checkpointKey(prefix))
+		if err != nil {
+			return err
+		}
+		if len(rsp.Kvs) > 0 {
+			revision, _ := strconv.ParseInt(string(rsp.Kvs[0].Value), 10, 64)
+			receiver.setRevision(prefix, revision, 0)
+		}
+	}
+	return nil
+}
+
+func (receiver *Replicator) checkpointKey(prefix string) string {
+	return strings.TrimSuffix(receiver.config.CheckpointKey, "/") + "/" + strings.TrimPrefix(prefix, "/")
+}
+
+// 检查点KEY在目标前缀下时，全量复制不能删除
+func (receiver *Replicator) isCheckpointKey(key string) bool {
+	return receiver.config.CheckpointKey != "" && strings.HasPrefix(key, strings.TrimSuffix(receiver.config.CheckpointKey, "/")+"/")
+}
+
+// 改写KEY
+func (receiver *Replicator) rewrite(key string) string {
+	for _, rule := range receiver.config.Rewrites {
+		if strings.HasPrefix(key, rule.From) {
+			return rule.To + key[len(rule.From):]
+		}
+	}
+	return key
+}
+
+// 全量复制：复制源的所有KEY，并删除目标中多出来的KEY
+func (receiver *Replicator) fullSync(ctx context.Context, prefix string) error {
+	srcRsp, err := receiver.src.Get(ctx, prefix, etcdV3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	dstPrefix := receiver.rewrite(prefix)
+	dstRsp, err := receiver.dst.Get(ctx, dstPrefix, etcdV3.WithPrefix(), etcdV3.WithKeysOnly())
+	if err != nil {
+		return err
+	}
+
+	var ops []etcdV3.Op
+	srcKeys := make(map[string]bool, len(srcRsp.Kvs))
+	for _, kv := range srcRsp.Kvs {
+		key := receiver.rewrite(string(kv.Key))
+		srcKeys[key] = true
+		ops = append(ops, etcdV3.OpPut(key, string(kv.Value)))
+	}
+	for _, kv := range dstRsp.Kvs {
+		if !srcKeys[string(kv.Key)] && !receiver.isCheckpointKey(string(kv.Key)) {
+			ops = append(ops, etcdV3.OpDelete(string(kv.Key)))
+		}
+	}
+	if err = receiver.apply(ctx, prefix, ops, srcRsp.Header.Revision); err != nil {
+		return err
+	}
+
+	receiver.lock.Lock()
+	receiver.stats.FullSyncs++
+	delete(receiver.resync, prefix)
+	receiver.lock.Unlock()
+	receiver.setRevision(prefix, srcRsp.Header.Revision, 0)
+	flog.Infof("Etcd跨集群复制：%s 全量复制完成，共%d个KEY，Revision：%d", prefix, len(srcRsp.Kvs), srcRsp.Header.Revision)
+	return nil
+}
+
+// 从已复制到的Revision开始监听，并将变化应用到目标
+func (receiver *Replicator) watch(ctx context.Context, prefix string) error {
+	revision := receiver.revision(prefix)
+	for watchRsp := range receiver.src.Watch(ctx, prefix, etcdV3.WithPrefix(), etcdV3.WithRev(revision+1), etcdV3.WithProgressNotify()) {
+		if err := watchRsp.Err(); err != nil {
+			return err
+		}
+		// 进度通知：之前的变化都已收到（只更新内存中的进度，不写检查点）
+		if watchRsp.IsProgressNotify() {
+			receiver.setRevision(prefix, watchRsp.Header.Revision, 0)
+			continue
+		}
+		if len(watchRsp.Events) == 0 {
+			continue
+		}
+
+		ops := make([]etcdV3.Op, 0, len(watchRsp.Events))
+		for _, event := range watchRsp.Events {
+			key := receiver.rewrite(string(event.Kv.Key))
+			if event.Type == mvccpb.DELETE {
+				ops = append(ops, etcdV3.OpDelete(key))
+			} else {
+				ops = append(ops, etcdV3.OpPut(key, string(event.Kv.Value)))
+			}
+		}
+		if err := receiver.apply(ctx, prefix, ops, watchRsp.Header.Revision); err != nil {
+			return err
+		}
+		receiver.setRevision(prefix, watchRsp.Header.Revision, len(watchRsp.Events))
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("监听已关闭")
+}
+
+// 按批次写入目标，检查点在最后写入
+// 中途失败时会从上一个检查点重新复制（写入是幂等的）
+func (receiver *Replicator) apply(ctx context.Context, prefix string, ops []etcdV3.Op, revision int64) error {
+	// 同一个事务中不能有重复的KEY，只保留最后一次变化
+	lastIndex := make(map[string]int, len(ops))
+	for index, op := range ops {
+		lastIndex[string(op.KeyBytes())] = index
+	}
+	uniqueOps := make([]etcdV3.Op, 0, len(lastIndex)+1)
+	for index, op := range ops {
+		if lastIndex[string(op.KeyBytes())] == index {
+			uniqueOps = append(uniqueOps, op)
+		}
+	}
+	if receiver.config.CheckpointKey != "" {
+		uniqueOps = append(uniqueOps, etcdV3.OpPut(receiver.checkpointKey(prefix), strconv.FormatInt(revision, 10)))
+	}
+
+	for start := 0; start < len(uniqueOps); start += receiver.config.BatchSize {
+		end := start + receiver.config.BatchSize
+		if end > len(uniqueOps) {
+			end = len(uniqueOps)
+		}
+		if _, err := receiver.dst.Txn(ctx).Then(uniqueOps[start:end]...).Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 定时刷新源的最新Revision
+func (receiver *Replicator) refreshStats(ctx context.Context) {
+	ticker := time.NewTicker(replicationStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		// 请求进度通知，使没有变化的前缀也能更新进度
+		_ = receiver.src.RequestProgress(ctx)
+		if rsp, err := receiver.src.Get(ctx, "\x00", etcdV3.WithCountOnly()); err == nil {
+			receiver.lock.Lock()
+			receiver.stats.SourceRevision = rsp.Header.Revision
+			if receiver.minRevision() >= rsp.Header.Revision {
+				receiver.caughtUp = time.Now()
+			}
+			receiver.lock.Unlock()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
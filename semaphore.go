@@ -0,0 +1,280 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/farseer-go/fs/trace"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// 信号量KEY的租约时间（单位s），进程崩溃后，最多在这个时间后释放许可
+const semaphoreTTL = 10
+
+// Semaphore 分布式信号量（按KEY的创建版本号先到先得）
+type Semaphore struct {
+	etcdCli      *etcdClient
+	traceManager trace.IManager
+	prefix       string // 信号量KEY前缀
+	permits      int    // 最大许可数
+	lock         sync.Mutex
+	session      *concurrency.Session
+	seq          int64            // 本实例的KEY序号
+	holds        []semaphoreEntry // 本实例已获取的许可（按获取顺序）
+}
+
+// SemaphoreHolder 信号量的持有者
+type SemaphoreHolder struct {
+	Key            string  // 持有者的KEY
+	Permits        int     // 持有的许可数
+	CreateRevision int64   // 排队时的集群Revision
+	Lease          LeaseID // 租约ID
+}
+
+type semaphoreEntry struct {
+	key     string
+	permits int
+}
+
+// NewSemaphore 分布式信号量，name：信号量KEY前缀，permits：最大许可数
+func NewSemaphore(client IClient, name string, permits int) *Semaphore {
+	cli := clientOf(client)
+	return &Semaphore{
+		etcdCli:      cli.etcdCli,
+		traceManager: cli.traceManager,
+		prefix:       strings.TrimSuffix(name, "/") + "/",
+		permits:      permits,
+	}
+}
+
+// Acquire 获取n个许可，许可不足时阻塞等待，直到获取成功或ctx取消
+func (receiver *Semaphore) Acquire(ctx context.Context, n int) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("SemaphoreAcquire", receiver.prefix, 0)
+	err := receiver.acquire(ctx, n, true)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	return err
+}
+
+// TryAcquire 尝试获取n个许可，许可不足时立即返回false
+func (receiver *Semaphore) TryAcquire(n int) (bool, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("SemaphoreTryAcquire", receiver.prefix, 0)
+	err := receiver.acquire(todo, n, false)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err == errSemaphoreBusy {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+var errSemaphoreBusy = fmt.Errorf("信号量许可不足")
+
+func (receiver *Semaphore) acquire(ctx context.Context, n int, wait bool) (err error) {
+	if n <= 0 || n > receiver.permits {
+		return fmt.Errorf("信号量许可数必须在1~%d之间：%d", receiver.permits, n)
+	}
+
+	session, key, err := receiver.newKey()
+	if err != nil {
+		return err
+	}
+
+	// 排队：写入带租约的KEY，创建版本号即为排队顺序
+	putRsp, err := receiver.etcdCli.Put(ctx, key, strconv.Itoa(n), etcdV3.WithLease(session.Lease()))
+	if err != nil {
+		return err
+	}
+
+	// 获取失败，退出排队
+	defer func() {
+		if err != nil {
+			_, _ = receiver.etcdCli.Delete(context.Background(), key)
+		}
+	}()
+
+	// 监听排在前面的KEY被删除或部分释放（在Get之前开始监听，避免漏掉事件）
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var watchChan etcdV3.WatchChan
+	if wait {
+		watchChan = receiver.etcdCli.Watch(watchCtx, receiver.prefix, etcdV3.WithPrefix(), etcdV3.WithRev(putRsp.Header.Revision+1))
+	}
+
+	for {
+		waiters, err := receiver.waiters(ctx)
+		if err != nil {
+			return err
+		}
+		if waiters.acquired(key, receiver.permits) {
+			receiver.lock.Lock()
+			receiver.holds = append(receiver.holds, semaphoreEntry{key: key, permits: n})
+			receiver.lock.Unlock()
+			return nil
+		}
+		if !wait {
+			return errSemaphoreBusy
+		}
+
+		select {
+		case watchRsp, ok := <-watchChan:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !ok {
+				return fmt.Errorf("信号量监听已关闭：%s", receiver.prefix)
+			}
+			if watchRsp.Err() != nil {
+				return watchRsp.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release 释放本实例持有的n个许可（按获取的先后顺序释放）
+func (receiver *Semaphore) Release(n int) error {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("SemaphoreRelease", receiver.prefix, 0)
+	var err error
+	defer func() { traceDetailEtcd.End(err) }()
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	held := 0
+	for _, entry := range receiver.holds {
+		held += entry.permits
+	}
+	if n <= 0 || n > held {
+		err = fmt.Errorf("释放的许可数必须在1~%d之间：%d", held, n)
+		return err
+	}
+
+	for n > 0 {
+		entry := &receiver.holds[0]
+		if entry.permits > n {
+			// 部分释放：保留原KEY（不改变排队顺序），只减少许可数
+			entry.permits -= n
+			_, err = receiver.etcdCli.Put(todo, entry.key, strconv.Itoa(entry.permits), etcdV3.WithLease(receiver.session.Lease()))
+			return err
+		}
+		if _, err = receiver.etcdCli.Delete(todo, entry.key); err != nil {
+			return err
+		}
+		n -= entry.permits
+		receiver.holds = receiver.holds[1:]
+	}
+	return nil
+}
+
+// Holders 当前持有许可的所有客户端（实时查询）
+func (receiver *Semaphore) Holders() ([]SemaphoreHolder, error) {
+	traceDetailEtcd := receiver.traceManager.TraceEtcd("SemaphoreHolders", receiver.prefix, 0)
+	waiters, err := receiver.waiters(todo)
+	defer func() { traceDetailEtcd.End(err) }()
+
+	if err != nil {
+		return nil, err
+	}
+	var holders []SemaphoreHolder
+	total := 0
+	for _, waiter := range waiters {
+		total += waiter.Permits
+		if total > receiver.permits {
+			break
+		}
+		holders = append(holders, waiter)
+	}
+	return holders, nil
+}
+
+// Permits 最大许可数
+func (receiver *Semaphore) Permits() int {
+	return receiver.permits
+}
+
+// Close 释放本实例持有的所有许可，并撤销租约
+func (receiver *Semaphore) Close() error {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	receiver.holds = nil
+	if receiver.session == nil {
+		return nil
+	}
+	err := receiver.session.Close()
+	receiver.session = nil
+	return err
+}
+
+// 生成本次排队的KEY：前缀/租约ID/序号
+func (receiver *Semaphore) newKey() (*concurrency.Session, string, error) {
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	// 租约过期（如网络长时间中断）后，旧租约下的KEY已被删除，重新创建会话
+	if receiver.session != nil && sessionExpired(receiver.session) {
+		receiver.holds = nil
+		receiver.session = nil
+	}
+	if receiver.session == nil {
+		session, err := concurrency.NewSession(receiver.etcdCli, concurrency.WithTTL(semaphoreTTL))
+		if err != nil {
+			return nil, "", err
+		}
+		receiver.session = session
+	}
+	receiver.seq++
+	return receiver.session, fmt.Sprintf("%s%x/%d", receiver.prefix, receiver.session.Lease(), receiver.seq), nil
+}
+
+// 会话的租约是否已过期
+func sessionExpired(session *concurrency.Session) bool {
+	select {
+	case <-session.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+type semaphoreWaiters []SemaphoreHolder
+
+// 获取所有排队中的KEY（按创建版本号排序）
+func (receiver *Semaphore) waiters(ctx context.Context) (semaphoreWaiters, error) {
+	rsp, err := receiver.etcdCli.Get(ctx, receiver.prefix, etcdV3.WithPrefix(), etcdV3.WithSort(etcdV3.SortByCreateRevision, etcdV3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	waiters := make(semaphoreWaiters, 0, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		permits, _ := strconv.Atoi(string(kv.Value))
+		waiters = append(waiters, SemaphoreHolder{
+			Key:            string(kv.Key),
+			Permits:        permits,
+			CreateRevision: kv.CreateRevision,
+			Lease:          LeaseID(kv.Lease),
+		})
+	}
+	return waiters, nil
+}
+
+// 排在key前面（含key）的许可总数不超过permits时，即为获取成功
+func (receiver semaphoreWaiters) acquired(key string, permits int) bool {
+	total := 0
+	for _, waiter := range receiver {
+		total += waiter.Permits
+		if total > permits {
+			return false
+		}
+		if waiter.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package etcd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// 是否使用TLS连接
+func (receiver etcdConfig) isTLS() bool {
+	return receiver.Tls || receiver.CaFile != "" || receiver.Ca != "" || receiver.CertFile != "" || receiver.Cert != "" || receiver.KeyFile != "" || receiver.Key != "" || receiver.ServerName != "" || receiver.InsecureSkipVerify
+}
+
+// 根据配置创建TLS配置，未开启TLS时返回nil
+func (receiver etcdConfig) tlsConfig() (*tls.Config, error) {
+	if !receiver.isTLS() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         receiver.ServerName,
+		InsecureSkipVerify: receiver.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	// CA证书（未配置时使用系统根证书）
+	ca, err := readPEM("Ca", receiver.CaFile, receiver.Ca)
+	if err != nil {
+		return nil, err
+	}
+	if ca != nil {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("TLS配置错误：CA证书格式不正确")
+		}
+	}
+
+	// 客户端证书（双向认证）
+	cert, err := readPEM("Cert", receiver.CertFile, receiver.Cert)
+	if err != nil {
+		return nil, err
+	}
+	key, err := readPEM("Key", receiver.KeyFile, receiver.Key)
+	if err != nil {
+		return nil, err
+	}
+	if (cert == nil) != (key == nil) {
+		return nil, fmt.Errorf("TLS配置错误：客户端证书Cert与私钥Key需同时配置")
+	}
+	if cert != nil {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("TLS配置错误：客户端证书或私钥不正确：%s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// 读取PEM内容：优先读取文件，其次使用内联的PEM（支持base64编码，或使用\n代替换行）
+func readPEM(name string, file string, inline string) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("TLS配置错误：读取%sFile失败：%s", name, err.Error())
+		}
+		return data, nil
+	}
+	if inline == "" {
+		return nil, nil
+	}
+	if strings.Contains(inline, "-----BEGIN") {
+		return []byte(strings.ReplaceAll(inline, `\n`, "\n")), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(inline)
+	if err != nil {
+		return nil, fmt.Errorf("TLS配置错误：%s不是有效的PEM或base64：%s", name, err.Error())
+	}
+	return data, nil
+}
+
+// 直接与endpoint进行TLS握手，用于获取明确的握手失败原因
+func tlsHandshake(endpoint string, tlsConfig *tls.Config, timeout time.Duration) error {
+	address := endpoint
+	if index := strings.Index(address, "://"); index > -1 {
+		address = address[index+3:]
+	}
+
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, config)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,30 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdV3 "go.etcd.io/etcd/client/v3"
+)
+
+// 从revision开始监听key，直到出现eventType事件或ctx取消
+func waitEvent(ctx context.Context, etcdCli *etcdClient, key string, revision int64, eventType mvccpb.Event_EventType) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for watchRsp := range etcdCli.Watch(watchCtx, key, etcdV3.WithRev(revision)) {
+		if err := watchRsp.Err(); err != nil {
+			return err
+		}
+		for _, event := range watchRsp.Events {
+			if event.Type == eventType {
+				return nil
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("监听已关闭：%s", key)
+}